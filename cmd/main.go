@@ -2,252 +2,664 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
-)
+	"time"
 
-var filesWithoutExtensions = map[string]bool{
-	"license": true,
-}
+	"github.com/efeertugrul/fileToProject/pkg/scaffold"
+)
 
-var ignoredFilesAndFolders = map[string]bool{
-	".gitignore": true,
-	".git":       true,
-}
+const (
+	modeCreate = iota // create folders and files from an input structure
+	modeScan          // print the tree structure of an existing path
+	modeDiff          // compare an input structure with an existing path
+	modeReadme        // embed the tree of an existing path into its README
+)
 
-type Node struct {
-	name     string
-	isDir    bool
-	children []*Node
-	parent   *Node
-	depth    int
+// modeNames maps the accepted -mode values to modes, the digits are kept for backward compatibility
+var modeNames = map[string]int{
+	"0":      modeCreate,
+	"create": modeCreate,
+	"1":      modeScan,
+	"scan":   modeScan,
+	"2":      modeDiff,
+	"diff":   modeDiff,
+	"3":      modeReadme,
+	"readme": modeReadme,
+	// long form of readme
+	"generate-readme": modeReadme,
 }
 
 func main() {
-	mode := flag.Int("mode", 0, "0: Create project folders and files\n1: Create project tree structure")
-	inputFile := flag.String("input", "", "Input file containing directory structure")
+	modeName := flag.String("mode", "create", "create (0): Create project folders and files\nscan (1): Create project tree structure\ndiff (2): Compare the -input structure with -path\nreadme or generate-readme (3): Embed the tree of -path into its README.md")
+	inputFile := flag.String("input", "", "Input file containing directory structure, several comma-separated files are merged")
 	outputDir := flag.String("output", ".", "Output directory where structure will be created")
 	path := flag.String("path", ".", "project path to create structure tree")
+	dryRun := flag.Bool("dry-run", false, "Print what would be created without touching disk")
+	force := flag.Bool("force", false, "Overwrite files that already exist")
+	verbose := flag.Bool("verbose", false, "Show a running [n/total] count in front of every created entry in mode 0")
+	quiet := flag.Bool("quiet", false, "Do not print every created directory and file in mode 0")
+	keepGoing := flag.Bool("keep-going", false, "Keep creating the rest of the structure when an entry fails in mode 0")
+	format := flag.String("format", "tree", "Output format for mode 1: tree, json, yaml, dot or flat, input format for mode 0: tree, yaml or json")
+	outFile := flag.String("o", "", "File to write mode 1 output to instead of stdout, or the README to update in mode 3")
+	maxDepth := flag.Int("max-depth", -1, "Maximum depth to descend in mode 1, at least 1, -1 for unlimited")
+	ignore := flag.String("ignore", "", "Comma-separated list of file and folder names or glob patterns to skip in mode 1")
+	templateDir := flag.String("template", "", "Directory of templates keyed by extension (e.g. go.tmpl) for new file bodies")
+	respectGitignore := flag.Bool("respect-gitignore", false, "Skip paths matched by the root .gitignore in mode 1")
+	followSymlinks := flag.Bool("follow-symlinks", false, "Descend into symlinked directories in mode 1")
+	showSize := flag.Bool("size", false, "Show file sizes and directory totals in mode 1")
+	ascii := flag.Bool("ascii", false, "Draw the mode 1 tree with ASCII characters instead of box-drawing characters")
+	commentsFrom := flag.String("comments", "", "Structure file whose # and // comments are kept in the mode 1 tree for the entries that still exist")
+	indent := flag.Int("indent", 4, "Columns per level in the mode 1 tree, at least 2")
+	indentSpaces := flag.Bool("indent-spaces", false, "Draw the levels of the mode 1 tree with spaces instead of vertical bars")
+	color := flag.String("color", "auto", "Color the mode 1 tree: auto (when writing to a terminal and NO_COLOR is not set), always or never")
+	fileColors := flag.Bool("file-colors", false, "Also color files by type in the mode 1 tree when colors are on")
+	icons := flag.Bool("icons", false, "Show a Nerd Font icon for each file type in the mode 1 tree")
+	concurrency := flag.Int("concurrency", runtime.GOMAXPROCS(0), "Number of directories to read in parallel in mode 1")
+	stat := flag.Bool("stat", false, "Record modification times and permissions in mode 1 json and yaml output, and restore them from json and yaml input in mode 0")
+	rootLabel := flag.String("root-name", "", "Text shown on the top line of the mode 1 tree instead of the name of the scanned directory")
+	rootPath := flag.String("root-path", "", "Show -path on the top line of the mode 1 tree, rel as given or abs as an absolute path")
+	strict := flag.Bool("strict", false, "Stop scanning with an error at the first directory that can not be read or at -max-entries, instead of warning")
+	maxEntries := flag.Int("max-entries", 0, "Stop scanning after this many entries and mark the tree as incomplete, 0 for unlimited")
+	relativeTo := flag.String("relative-to", "", "Print the paths of -format flat in mode 1 and of mode 2 relative to this parent of -path")
+	onlyDirs := flag.Bool("only-dirs", false, "Only show directories in mode 1, like tree -d")
+	onlyFiles := flag.Bool("only-files", false, "Only show files in mode 1 and the directories on the way to them, best with -format flat")
+	flatDirs := flag.Bool("flat-dirs", false, "List directories with a trailing slash in -format flat, not only files")
+	lines := flag.Bool("lines", false, "Show line counts of text files and directory totals in mode 1")
+	since := flag.Duration("since", 0, "Only show files modified within this duration in mode 1, e.g. 24h")
+	hash := flag.Bool("hash", false, "Record the SHA-256 of every file in mode 1 json output, and compare contents in mode 2")
+	sortOrder := flag.String("sort", "dirs-first", "Order of entries in mode 1: name, dirs-first, files-first, size (largest first) or mtime (newest first)")
+	reverse := flag.Bool("reverse", false, "Reverse the -sort order")
+	normalize := flag.String("normalize", "", "Unicode normalization form for input and scanned names, nfc or nfd, so composed and decomposed names like é compare equal")
+	maxNesting := flag.Int("max-nesting", scaffold.DefaultMaxNesting, "Fail on inputs and directories nested deeper than this many levels, -1 for unlimited")
+	includeHidden := flag.Bool("include-hidden", false, "Show files and folders starting with a dot in mode 1")
+	yes := flag.Bool("yes", false, "Do not ask for confirmation before creating a large structure in mode 0")
+	confirmOver := flag.Int("confirm-over", 50, "Ask for confirmation in mode 0 when the structure has more entries than this, 0 never asks")
+	archive := flag.String("archive", "", "Write the mode 0 structure to a .zip, .tar or .tar.gz archive instead of -output")
+	jsonSchema := flag.Bool("json-schema", false, "Print the JSON Schema of the json input and output format and exit")
+	validate := flag.Bool("validate", false, "Check the mode 0 input for unreadable lines, duplicate entries and invalid names without creating anything, exit 1 on problems")
+	emit := flag.String("emit", "", "Print a script that creates the structure instead of creating it in mode 0: sh or ps1")
+	watch := flag.Bool("watch", false, "Keep running in mode 0 and create new entries whenever the -input files change, combine with -prune to remove deleted ones")
+	prune := flag.Bool("prune", false, "Remove everything in the output directory that is not in the input after creating it in mode 0")
+	gitkeep := flag.Bool("gitkeep", false, "Put an empty -gitkeep-name file in every new directory that has no entries in mode 0, so git keeps it")
+	placeholderName := flag.String("gitkeep-name", ".gitkeep", "Name of the file -gitkeep creates, e.g. .keep")
+	createDepth := flag.Int("create-depth", 0, "Only create this many levels of the input in mode 0 and skip deeper entries, 0 for all")
+	createOutput := flag.Bool("create-output", false, "Create the -output directory in mode 0 when it does not exist")
+	noRoot := flag.Bool("no-root", false, "Create the entries of the single top level directory of the input straight into -output in modes 0 and 2, instead of in a directory with its name")
+	rootName := flag.String("root", "", "Create everything inside a directory with this name in mode 0, replacing a single top level directory")
+	from := flag.String("from", "", "Directory to copy into -output in mode 0, with its file contents, instead of reading -input")
+	templateRepo := flag.String("template-repo", "", "Git URL of a public repository to print in mode 1 or recreate as an empty skeleton in mode 0")
+	expand := flag.Bool("expand", false, "Expand $VAR and ${VAR} in input names from -var and the environment, implied by -var")
+	strictVars := flag.Bool("strict-vars", false, "Fail on undefined variables instead of expanding them to an empty string")
+	vars := make(map[string]string)
+	flag.Func("var", "KEY=VALUE variable for $KEY in input names and {{KEY}} in created names and contents, can be repeated", func(s string) error {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected KEY=VALUE, got %q", s)
+		}
+		vars[key] = value
+		return nil
+	})
+	filesWithoutExt := flag.String("files-without-ext", "", "Comma-separated extensionless names that are files in mode 0, added to the defaults like LICENSE")
+	rawNames := flag.Bool("raw-names", false, "Keep input names exactly as written after the tree connector, with leading dashes, box characters and spaces, instead of trimming them with a warning")
+	explicitDirs := flag.Bool("explicit-dirs", false, "Read input names without a trailing slash as files, implied when the input ends with the N directories, M files line of mode 1")
+	lenient := flag.Bool("lenient", false, "Find the drawn tree in the input and ignore the text around it, for a whole chat message or document pasted as it is")
+	markdown := flag.Bool("markdown", false, "Read the input as Markdown and use its first tree code block, implied for .md files")
 
 	flag.Parse()
 
-	switch *mode {
-	case 0:
-		if *inputFile == "" {
-			fmt.Println("Error: Input file must be specified with -i flag")
+	if *jsonSchema {
+		os.Stdout.Write(scaffold.JSONSchema)
+		return
+	}
+
+	mode, ok := modeNames[strings.ToLower(*modeName)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown mode %q, use create (0), scan (1), diff (2) or readme/generate-readme (3)\n", *modeName)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *noRoot && *rootName != "" {
+		fmt.Fprintln(os.Stderr, "Error: -root and -no-root can not be used together")
+		os.Exit(1)
+	}
+	if *onlyDirs && *onlyFiles {
+		fmt.Fprintln(os.Stderr, "Error: -only-dirs and -only-files can not be used together")
+		os.Exit(1)
+	}
+	// ScanOptions treats 0 as unlimited, so a depth of 0 would silently scan everything
+	if *maxDepth == 0 {
+		fmt.Fprintln(os.Stderr, "Error: -max-depth must be at least 1, use -1 for unlimited")
+		os.Exit(1)
+	}
+
+	scanOpts := scaffold.ScanOptions{
+		RespectGitignore: *respectGitignore,
+		FollowSymlinks:   *followSymlinks,
+		WithSize:         *showSize,
+		IncludeHidden:    *includeHidden,
+		Concurrency:      *concurrency,
+		WithModTime:      *stat,
+		WithLines:        *lines,
+		WithMode:         *stat,
+		WithHash:         *hash,
+		Sort:             *sortOrder,
+		Reverse:          *reverse,
+		MaxNesting:       *maxNesting,
+		Normalize:        *normalize,
+		Strict:           *strict,
+		MaxEntries:       *maxEntries,
+	}
+	if *since > 0 {
+		scanOpts.Since = time.Now().Add(-*since)
+	}
+	// merge user supplied ignores with the defaults
+	for _, name := range strings.Split(*ignore, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			scanOpts.Ignore = append(scanOpts.Ignore, name)
+		}
+	}
+	if *maxDepth > 0 {
+		scanOpts.MaxDepth = *maxDepth
+	}
+
+	switch mode {
+	case modeCreate:
+		// without -input the structure is read from stdin when something is piped in
+		if *inputFile == "" && *templateRepo == "" && *from == "" && !stdinIsPiped() {
+			fmt.Fprintln(os.Stderr, "Error: Input file must be specified with -input flag, use - for stdin")
 			flag.Usage()
 			os.Exit(1)
 		}
 
-		root, err := parseTree(*inputFile)
-		if err != nil {
-			fmt.Printf("Error parsing structure: %v\n", err)
+		if *watch && (*inputFile == "" || slices.Contains(strings.Split(*inputFile, ","), "-") || *archive != "") {
+			fmt.Fprintln(os.Stderr, "Error: -watch needs -input files and can not be used with stdin or -archive")
 			os.Exit(1)
 		}
 
-		fmt.Printf("Creating project structure in: %s\n", *outputDir)
-		if err := createFromTree(*outputDir, root); err != nil {
-			fmt.Printf("Error creating project structure: %v\n", err)
+		// a mistyped -output should not silently create a new directory tree
+		if _, err := os.Stat(*outputDir); errors.Is(err, fs.ErrNotExist) && !*createOutput && *archive == "" && *emit == "" && !*validate {
+			fmt.Fprintf(os.Stderr, "Error: output directory %s does not exist, pass -create-output to create it\n", *outputDir)
 			os.Exit(1)
 		}
-		fmt.Println("Project structure created successfully!")
-	case 1:
-		root, err := createTree(*path, 0)
+
+		parseOpts, err := parseOptions(*format, *markdown, *filesWithoutExt)
 		if err != nil {
-			fmt.Printf("Error creating tree: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		parseOpts.Expand, parseOpts.Vars, parseOpts.StrictVars = *expand || len(vars) > 0, vars, *strictVars
+		parseOpts.MaxNesting, parseOpts.Normalize = *maxNesting, *normalize
+		parseOpts.RawNames, parseOpts.Lenient, parseOpts.ExplicitDirs = *rawNames, *lenient, *explicitDirs
+
+		// lint the input for CI, nothing is created and no progress is printed
+		if *validate {
+			if problems := validateInputs(*inputFile, parseOpts, *rootName, *noRoot); problems > 0 {
+				fmt.Fprintf(os.Stderr, "%d problem(s) found\n", problems)
+				os.Exit(1)
+			}
+			fmt.Fprintln(os.Stderr, "No problems found.")
+			return
+		}
 
-		printTree(root)
-	default:
-		fmt.Println("invalid mode")
-		flag.Usage()
-	}
-}
-
-func parseTree(filename string) (*Node, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
+		basePath := *outputDir
+		var root *scaffold.Node
+		if *from != "" {
+			// the contents of the source directory go straight into output
+			root, err = scaffold.Scan(*from, scanOpts)
+		} else if *templateRepo != "" {
+			// the scanned root is the repository itself, so it is created as a directory under output
+			root, err = scanRepo(*templateRepo, scanOpts)
+			if err == nil && !*noRoot {
+				name := root.Name()
+				if *rootName != "" {
+					name = *rootName
+				}
+				basePath = filepath.Join(*outputDir, name)
+			}
+		} else {
+			root, err = parseInput(*inputFile, parseOpts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing structure: %v\n", err)
+			os.Exit(1)
+		}
 
-	scanner := bufio.NewScanner(file)
-	var nodes []*Node
-	root := &Node{name: ".", isDir: true}
-	currentParent := root
-	var currentDepth int = 0
+		if *rootName != "" && *templateRepo == "" {
+			scaffold.SetRootDir(root, *rootName)
+		}
+		if *noRoot && *templateRepo == "" && !scaffold.StripRootDir(root) {
+			fmt.Fprintln(os.Stderr, "Error: -no-root needs a structure with a single top level directory")
+			os.Exit(1)
+		}
 
-	for scanner.Scan() {
-		line := strings.TrimRight(scanner.Text(), " ")
-		print(line + "\n")
-		if line == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
-			continue
+		// an archive holds the paths below output and is only written once Build succeeded
+		var archiveFS *scaffold.ArchiveFS
+		var archiveData bytes.Buffer
+		if *archive != "" {
+			archiveFormat, ok := scaffold.ArchiveFormat(*archive)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Error: unknown archive type %s, use .zip, .tar or .tar.gz\n", *archive)
+				os.Exit(1)
+			}
+			archiveFS, err = scaffold.NewArchiveFS(&archiveData, archiveFormat)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if basePath, err = filepath.Rel(*outputDir, basePath); err != nil {
+				basePath = "."
+			}
 		}
 
-		// Calculate depth and name
-		depth, name := parseLine(line)
-		if name == "" {
-			continue
+		opts := scaffold.BuildOptions{DryRun: *dryRun, Force: *force, Quiet: *quiet, KeepGoing: *keepGoing, Verbose: *verbose, ModTimes: *stat, CopySources: *from != "", Vars: vars, MaxDepth: *createDepth}
+		if *gitkeep {
+			opts.Placeholder = *placeholderName
+		}
+		if archiveFS != nil {
+			opts.FS = archiveFS
+		}
+		if *templateDir != "" {
+			opts.Templates, err = scaffold.LoadTemplates(*templateDir)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading templates: %v\n", err)
+				os.Exit(1)
+			}
 		}
 
-		// Adjust parent based on depth
-		if depth > currentDepth {
-			// Child of previous node
-			currentParent = nodes[len(nodes)-1]
-			currentDepth = depth
-		} else if depth < currentDepth {
-			// Move up the tree
-			for currentDepth > depth {
-				currentParent = currentParent.parent
-				currentDepth--
+		// a script creates the structure later, somewhere else, so nothing is touched here
+		if *emit != "" {
+			if err := scaffold.WriteScript(os.Stdout, root, *emit, opts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing script: %v\n", err)
+				os.Exit(1)
 			}
+			return
 		}
 
-		node := &Node{
-			name:   name,
-			isDir:  !strings.Contains(name, ".") && !filesWithoutExtensions[strings.ToLower(name)],
-			parent: currentParent,
-			depth:  depth,
+		// only ask when someone can answer, a piped stdin or -force means the caller is sure
+		if !*yes && !*force && !*dryRun && *confirmOver > 0 && !stdinIsPiped() {
+			dirs, files := countEntries(root, *createDepth)
+			if dirs+files > *confirmOver && !confirm(fmt.Sprintf("About to create %d directories and %d files in %s. Continue? [y/N] ", dirs, files, basePath)) {
+				fmt.Fprintln(os.Stderr, "Aborted, nothing was created.")
+				os.Exit(1)
+			}
 		}
 
-		currentParent.children = append(currentParent.children, node)
-		nodes = append(nodes, node)
-		currentDepth = depth
-	}
+		if !*quiet {
+			target := *outputDir
+			if *archive != "" {
+				target = *archive
+			}
+			fmt.Fprintf(os.Stderr, "Creating project structure in: %s\n", target)
+		}
+		if _, err := scaffold.Build(basePath, root, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating project structure: %v\n", err)
+			os.Exit(1)
+		}
+		if archiveFS != nil && !*dryRun {
+			if err := archiveFS.Close(); err == nil {
+				err = os.WriteFile(*archive, archiveData.Bytes(), 0666)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing archive: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if *prune && archiveFS == nil {
+			if err := pruneOutput(basePath, root, scanOpts, opts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error pruning project structure: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if *dryRun {
+			fmt.Fprintln(os.Stderr, "Dry run finished, nothing was created.")
+		} else {
+			fmt.Fprintln(os.Stderr, "Project structure created successfully!")
+		}
 
-	return root, scanner.Err()
-}
+		if *watch {
+			fmt.Fprintf(os.Stderr, "Watching %s for changes, press Ctrl+C to stop\n", *inputFile)
+			watchInput(*inputFile, func() error {
+				root, err := parseInput(*inputFile, parseOpts)
+				if err != nil {
+					return fmt.Errorf("error parsing structure: %v", err)
+				}
+				if *rootName != "" {
+					scaffold.SetRootDir(root, *rootName)
+				}
+				if *noRoot && !scaffold.StripRootDir(root) {
+					return errors.New("-no-root needs a structure with a single top level directory")
+				}
+
+				// everything that existed was created by an earlier run, only report what is new
+				rebuildOpts := opts
+				rebuildOpts.Quiet, rebuildOpts.Log = true, io.Discard
+				created, err := scaffold.Build(basePath, root, rebuildOpts)
+				for _, p := range created {
+					if !*quiet {
+						fmt.Fprintf(os.Stderr, "Created: %s\n", p)
+					}
+				}
+				if err != nil {
+					return fmt.Errorf("error creating project structure: %v", err)
+				}
+				if *prune {
+					if err := pruneOutput(basePath, root, scanOpts, opts); err != nil {
+						return fmt.Errorf("error pruning project structure: %v", err)
+					}
+				}
+				return nil
+			})
+		}
+	case modeScan:
+		var root *scaffold.Node
+		var err error
+		if *templateRepo != "" {
+			root, err = scanRepo(*templateRepo, scanOpts)
+		} else {
+			root, err = scaffold.Scan(*path, scanOpts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating tree: %v\n", err)
+			os.Exit(1)
+		}
 
-func parseLine(line string) (int, string) {
-	// Count tree characters to determine depth
-	var depth int = 0
-	chars := []rune(line)
-	for i := 0; i < len(chars); i++ {
-		switch chars[i] {
-		case '│', '├', '└':
-			// Skip tree characters but count depth
-			depth++
-
-			// if i+3 < len(chars) && chars[i+1] == '─' && chars[i+2] == '─' && chars[i+3] == ' ' {
-			// 	i += 3
-			// }
-		case ' ', '-', '─':
-			continue
-		default:
-			// Clean up name (remove comments and trim)
-			name := string(chars[i:])
-			name = strings.Split(name, "#")[0]
-			name = strings.Trim(name, " ─│├└")
-			return depth, name
+		if *commentsFrom != "" {
+			parseOpts, _ := parseOptions("tree", *markdown, *filesWithoutExt)
+			parseOpts.RawNames, parseOpts.Lenient, parseOpts.ExplicitDirs = *rawNames, *lenient, *explicitDirs
+			annotated, err := scaffold.ParseFile(*commentsFrom, parseOpts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", *commentsFrom, err)
+				os.Exit(1)
+			}
+			// the top directory of the structure file is the scanned directory
+			if top := annotated.Children(); len(top) == 1 && top[0].IsDir() {
+				annotated = top[0]
+			}
+			scaffold.CopyComments(root, annotated)
+		}
+		if *onlyDirs {
+			scaffold.OnlyDirs(root)
+		} else if *onlyFiles {
+			scaffold.OnlyFiles(root)
 		}
-	}
-	return 0, ""
-}
 
-func createFromTree(basePath string, node *Node) error {
-	for _, child := range node.children {
-		fullPath := filepath.Join(basePath, child.name)
+		out := os.Stdout
+		if *outFile != "" {
+			out, err = os.Create(*outFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+				os.Exit(1)
+			}
+			defer out.Close()
+		}
 
-		if child.isDir {
-			fmt.Printf("Creating directory: %s\n", fullPath)
-			if err := os.MkdirAll(fullPath, 0755); err != nil {
-				return fmt.Errorf("error creating directory %s: %v", fullPath, err)
+		printOpts := scaffold.PrintOptions{FlatDirs: *flatDirs, ShowSize: *showSize, ShowLines: *lines, ASCII: *ascii, FileColors: *fileColors, Icons: *icons, Indent: *indent, IndentSpaces: *indentSpaces, Comments: *commentsFrom != ""}
+		// the flat format needs the directories listed when they are all that is left
+		if *onlyDirs {
+			printOpts.FlatDirs = true
+		} else if *onlyFiles {
+			printOpts.FlatDirs = false
+		}
+		if *relativeTo != "" {
+			if printOpts.PathPrefix, err = relativePrefix(*relativeTo, *path); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
-			if err := createFromTree(fullPath, child); err != nil {
-				return err
+		}
+		switch {
+		case *rootLabel != "":
+			printOpts.RootName = *rootLabel
+		case *rootPath == "rel" && *templateRepo == "":
+			printOpts.RootName = filepath.ToSlash(filepath.Clean(*path))
+		case *rootPath == "abs" && *templateRepo == "":
+			abs, err := filepath.Abs(*path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error resolving %s: %v\n", *path, err)
+				os.Exit(1)
 			}
-		} else {
-			fmt.Printf("Creating file: %s\n", fullPath)
-			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
-				return fmt.Errorf("error creating parent directories for %s: %v", fullPath, err)
+			printOpts.RootName = filepath.ToSlash(abs)
+		case *rootPath != "" && *rootPath != "rel" && *rootPath != "abs":
+			fmt.Fprintf(os.Stderr, "Error: unknown -root-path %q, use rel or abs\n", *rootPath)
+			os.Exit(1)
+		}
+		printOpts.Color, err = useColor(*color, out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := scaffold.Render(out, root, *format, printOpts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing tree: %v\n", err)
+			os.Exit(1)
+		}
+	case modeDiff:
+		if *inputFile == "" && !stdinIsPiped() {
+			fmt.Fprintln(os.Stderr, "Error: Input file must be specified with -input flag, use - for stdin")
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		parseOpts, err := parseOptions(*format, *markdown, *filesWithoutExt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		parseOpts.Expand, parseOpts.Vars, parseOpts.StrictVars = *expand || len(vars) > 0, vars, *strictVars
+		parseOpts.MaxNesting, parseOpts.Normalize = *maxNesting, *normalize
+		parseOpts.RawNames, parseOpts.Lenient, parseOpts.ExplicitDirs = *rawNames, *lenient, *explicitDirs
+		want, err := parseInput(*inputFile, parseOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing structure: %v\n", err)
+			os.Exit(1)
+		}
+		if *rootName != "" {
+			scaffold.SetRootDir(want, *rootName)
+		}
+		if *noRoot && !scaffold.StripRootDir(want) {
+			fmt.Fprintln(os.Stderr, "Error: -no-root needs a structure with a single top level directory")
+			os.Exit(1)
+		}
+		have, err := scaffold.Scan(*path, scanOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating tree: %v\n", err)
+			os.Exit(1)
+		}
+
+		prefix := ""
+		if *relativeTo != "" {
+			if prefix, err = relativePrefix(*relativeTo, *path); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
-			if _, err := os.Create(fullPath); err != nil {
-				return fmt.Errorf("error creating file %s: %v", fullPath, err)
+			if prefix != "" {
+				prefix += "/"
 			}
 		}
-	}
-	return nil
-}
 
-// this function will create a tree structure in the given path and subdirectories
-func createTree(path string, depth int) (*Node, error) {
+		// - is missing from the path, + is only on disk and ~ has different contents,
+		// the exit code is 1 like diff
+		changes := scaffold.Diff(want, have)
+		for _, change := range changes {
+			sign := "-"
+			if change.Added {
+				sign = "+"
+			} else if change.Changed {
+				sign = "~"
+			}
+			fmt.Printf("%s %s%s\n", sign, prefix, change.Path)
+		}
+		if len(changes) > 0 {
+			os.Exit(1)
+		}
+	case modeReadme:
+		root, err := scaffold.Scan(*path, scanOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating tree: %v\n", err)
+			os.Exit(1)
+		}
 
-	// start with the root directory and create the tree structure recursively
-	directoryName := filepath.Base(path)
-	if ignoredFilesAndFolders[directoryName] {
-		// skip the ignored directory
-		return nil, nil
+		var tree strings.Builder
+		scaffold.Print(&tree, root, scaffold.PrintOptions{ShowSize: *showSize, ShowLines: *lines, ASCII: *ascii})
+		readme := *outFile
+		if readme == "" {
+			readme = filepath.Join(*path, "README.md")
+		}
+		if err := updateReadme(readme, tree.String()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating README: %v\n", err)
+			os.Exit(1)
+		}
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "Updated the project structure in %s\n", readme)
+		}
 	}
+}
 
-	parent := &Node{
-		name:  directoryName,
-		isDir: true,
-		depth: depth,
+// parseOptions returns the options for reading an input structure in the given -format
+func parseOptions(format string, markdown bool, filesWithoutExt string) (scaffold.ParseOptions, error) {
+	opts := scaffold.ParseOptions{Markdown: markdown}
+	for _, name := range strings.Split(filesWithoutExt, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			opts.FilesWithoutExt = append(opts.FilesWithoutExt, name)
+		}
 	}
+	switch format {
+	case "tree":
+	case "yaml":
+		opts.YAML = true
+	case "json":
+		opts.JSON = true
+	default:
+		return opts, fmt.Errorf("unknown input format %q, use tree, yaml or json", format)
+	}
+	return opts, nil
+}
 
-	// list the files and directories in the current directory
-	files, err := os.ReadDir(path)
+// relativePrefix returns the slash separated path of dir relative to base, "" when
+// they are the same directory. base has to be dir or one of its parents
+func relativePrefix(base, dir string) (string, error) {
+	absBase, err := filepath.Abs(base)
 	if err != nil {
-		fmt.Println(err)
-
-		return nil, fmt.Errorf("error reading directory %s: %w", path, err)
+		return "", fmt.Errorf("error resolving %s: %v", base, err)
 	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("error resolving %s: %v", dir, err)
+	}
+	rel, err := filepath.Rel(absBase, absDir)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("-relative-to %s is not %s or a parent of it", base, dir)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
 
-	for i := range files {
-		if files[i].IsDir() {
-			// recursively create the tree for the subdirectory
-			subDirPath := filepath.Join(path, files[i].Name())
-			dirNode, err := createTree(subDirPath, depth+1)
-			if err != nil {
-				fmt.Println(err)
-
-				return nil, fmt.Errorf("error creating tree for directory %s: %w", subDirPath, err)
-			}
+// pruneOutput removes everything in basePath that is not in root. in a dry run the
+// output directory may not exist yet, then there is nothing to remove
+func pruneOutput(basePath string, root *scaffold.Node, scanOpts scaffold.ScanOptions, opts scaffold.BuildOptions) error {
+	existing, err := scaffold.Scan(basePath, scanOpts)
+	if err != nil {
+		if opts.DryRun && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return scaffold.Prune(basePath, root, existing, opts)
+}
 
-			// add the subdirectory node to the parent node
-			if dirNode != nil {
-				parent.children = append(parent.children, dirNode)
-			}
+// parseInput parses the structure in the named file, or stdin for "" and "-". a
+// comma-separated list of files is parsed in order and merged into one tree
+func parseInput(names string, opts scaffold.ParseOptions) (*scaffold.Node, error) {
+	var root *scaffold.Node
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		var tree *scaffold.Node
+		var err error
+		if name == "" || name == "-" {
+			tree, err = scaffold.Parse(os.Stdin, opts)
 		} else {
-			if ignoredFilesAndFolders[files[i].Name()] {
-				// skip the ignored file
-				continue
-			}
-
-			node := &Node{
-				name:   files[i].Name(),
-				isDir:  false,
-				parent: parent,
-				depth:  parent.depth + 1,
+			tree, err = scaffold.ParseFile(name, opts)
+		}
+		if err != nil {
+			if name != "" && strings.Contains(names, ",") {
+				return nil, fmt.Errorf("%s: %w", name, err)
 			}
+			return nil, err
+		}
 
-			parent.children = append(parent.children, node)
+		if root == nil {
+			root = tree
+		} else if err := scaffold.Merge(root, tree); err != nil {
+			return nil, fmt.Errorf("error merging %s: %w", name, err)
 		}
 	}
-
-	return parent, nil
+	return root, nil
 }
 
-func printTree(node *Node) {
-
-	for i := range node.depth {
-		if i < (node.depth)-1 {
-			fmt.Print("│   ")
-		} else {
-			fmt.Print("│── ")
+// useColor resolves the -color setting for output written to out. auto colors only
+// when out is a terminal and NO_COLOR is not set, see https://no-color.org
+func useColor(mode string, out *os.File) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if _, ok := os.LookupEnv("NO_COLOR"); ok {
+			return false, nil
 		}
+		info, err := out.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
 	}
+	return false, fmt.Errorf("unknown color mode %q, use auto, always or never", mode)
+}
 
-	if node.isDir {
-		fmt.Printf("%s/\n", node.name)
-		for i := range node.children {
-			printTree(node.children[i])
+// countEntries counts the directories and files below root, down to maxDepth levels
+// when it is positive
+func countEntries(root *scaffold.Node, maxDepth int) (int, int) {
+	var dirs, files int
+	root.Walk(func(n *scaffold.Node) error {
+		switch {
+		case n == root:
+		case n.IsDir():
+			dirs++
+		default:
+			files++
+		}
+		if maxDepth > 0 && n.Depth()-root.Depth() >= maxDepth {
+			return scaffold.SkipDir
 		}
-	} else {
-		fmt.Printf("%s\n", node.name)
+		return nil
+	})
+	return dirs, files
+}
+
+// confirm prints question to stderr and reports whether the answer read from stdin is yes
+func confirm(question string) bool {
+	fmt.Fprint(os.Stderr, question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// stdinIsPiped reports whether stdin is a pipe or file rather than a terminal
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
 	}
+	return info.Mode()&os.ModeCharDevice == 0
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// markers around the generated structure section of a README
+const (
+	structureStart = "<!-- structure:start -->"
+	structureEnd   = "<!-- structure:end -->"
+)
+
+// updateReadme writes tree as a fenced block between the structure markers of the
+// README at path. everything outside the markers is kept, and when the markers are
+// missing the block is appended to the end of the file along with them
+func updateReadme(path, tree string) error {
+	readme, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	section := structureStart + "\n```\n" + tree + "```\n" + structureEnd
+	start := bytes.Index(readme, []byte(structureStart))
+	end := bytes.Index(readme, []byte(structureEnd))
+
+	var out []byte
+	switch {
+	case start >= 0 && end > start:
+		out = append(out, readme[:start]...)
+		out = append(out, section...)
+		out = append(out, readme[end+len(structureEnd):]...)
+	case start >= 0 || end >= 0:
+		return fmt.Errorf("%s has only one of the %s and %s markers", path, structureStart, structureEnd)
+	default:
+		out = readme
+		if len(out) > 0 && !bytes.HasSuffix(out, []byte("\n")) {
+			out = append(out, '\n')
+		}
+		if len(out) > 0 {
+			out = append(out, '\n')
+		}
+		out = append(out, section+"\n"...)
+	}
+
+	if err := os.WriteFile(path, out, 0666); err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/efeertugrul/fileToProject/pkg/scaffold"
+)
+
+// scanRepo shallow clones a git repository into a temporary directory, scans it and
+// removes the clone again. the root of the returned tree is named after the repository
+func scanRepo(url string, opts scaffold.ScanOptions) (*scaffold.Node, error) {
+	// git would read a URL starting with - as one of its options
+	if strings.HasPrefix(url, "-") {
+		return nil, fmt.Errorf("invalid repository URL %q", url)
+	}
+	tmp, err := os.MkdirTemp("", "fileToProject-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	dir := filepath.Join(tmp, repoName(url))
+	cmd := exec.Command("git", "clone", "--depth", "1", "--quiet", "--", url, dir)
+	// never wait for a username or password, only public repositories are supported
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error cloning %s: %v", url, err)
+	}
+
+	return scaffold.Scan(dir, opts)
+}
+
+// repoName returns the repository name in a git URL, e.g. tool for
+// https://github.com/user/tool.git or git@github.com:user/tool
+func repoName(url string) string {
+	url = strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	if url == "" || url == "." || url == ".." {
+		return "repo"
+	}
+	return url
+}
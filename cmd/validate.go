@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/efeertugrul/fileToProject/pkg/scaffold"
+)
+
+// validateInputs parses every comma-separated input on its own, so a file declared in
+// two of them is not merged away, and prints each problem found as "name: line N: ...".
+// rootName and noRoot change the top level like -root and -no-root do before creating,
+// so the same tree is checked that would be built. it returns the number of problems
+func validateInputs(names string, opts scaffold.ParseOptions, rootName string, noRoot bool) int {
+	problems := 0
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		label := name
+		var root *scaffold.Node
+		var err error
+		if name == "" || name == "-" {
+			label = "stdin"
+			root, err = scaffold.Parse(os.Stdin, opts)
+		} else {
+			root, err = scaffold.ParseFile(name, opts)
+		}
+
+		var parseErr *scaffold.ParseError
+		if errors.As(err, &parseErr) {
+			for _, line := range parseErr.Lines {
+				fmt.Printf("%s: line %d: can not parse %q\n", label, line.Number, line.Text)
+			}
+			problems += len(parseErr.Lines)
+			continue
+		}
+		if err != nil {
+			// json inputs report every schema mismatch on its own line
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Printf("%s: %s\n", label, line)
+				problems++
+			}
+			continue
+		}
+
+		if rootName != "" {
+			scaffold.SetRootDir(root, rootName)
+		}
+		if noRoot && !scaffold.StripRootDir(root) {
+			fmt.Printf("%s: -no-root needs a structure with a single top level directory\n", label)
+			problems++
+			continue
+		}
+		for _, problem := range scaffold.Validate(root) {
+			fmt.Printf("%s: %s\n", label, problem)
+			problems++
+		}
+	}
+	return problems
+}
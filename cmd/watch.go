@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// watchInterval is how often -watch checks the input files for changes
+const watchInterval = 500 * time.Millisecond
+
+// watchInput calls rebuild every time one of the comma-separated input files changes,
+// until the process is interrupted. files are polled, and a change is only picked up
+// once they stayed the same for one interval, so an editor saving in several writes
+// triggers a single rebuild. errors are printed and watching goes on
+func watchInput(names string, rebuild func() error) {
+	last := inputStamp(names)
+	for {
+		time.Sleep(watchInterval)
+		current := inputStamp(names)
+		if current == last {
+			continue
+		}
+		for {
+			time.Sleep(watchInterval)
+			next := inputStamp(names)
+			if next == current {
+				break
+			}
+			current = next
+		}
+		last = current
+
+		if err := rebuild(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+}
+
+// inputStamp sums up the size and modification time of every input file, a changed
+// stamp means one of them was written, removed or recreated
+func inputStamp(names string) string {
+	var sb strings.Builder
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		info, err := os.Stat(name)
+		if err != nil {
+			fmt.Fprintf(&sb, "%s missing\n", name)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s %d %d\n", name, info.Size(), info.ModTime().UnixNano())
+	}
+	return sb.String()
+}
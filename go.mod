@@ -1,3 +1,8 @@
 module github.com/efeertugrul/fileToProject
 
 go 1.24.1
+
+require (
+	golang.org/x/text v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,209 @@
+package scaffold
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ArchiveFS is an FS that collects everything Build creates and writes it to an
+// archive instead of the disk when it is closed. paths are stored relative to the
+// basePath given to Build, so Build is usually called with "."
+type ArchiveFS struct {
+	w       io.Writer
+	format  string
+	entries []*archiveEntry
+	byPath  map[string]*archiveEntry
+}
+
+// archiveEntry is a directory or file waiting to be written to the archive
+type archiveEntry struct {
+	name    string // slash separated, directories end in /
+	isDir   bool
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// ArchiveFormat returns the format for an archive file name: zip, tar or tar.gz
+func ArchiveFormat(name string) (string, bool) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip", true
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz", true
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar", true
+	}
+	return "", false
+}
+
+// NewArchiveFS returns an ArchiveFS that writes a zip, tar or tar.gz archive to w
+func NewArchiveFS(w io.Writer, format string) (*ArchiveFS, error) {
+	switch format {
+	case "zip", "tar", "tar.gz":
+	default:
+		return nil, fmt.Errorf("unknown archive format %q, use zip, tar or tar.gz", format)
+	}
+	return &ArchiveFS{w: w, format: format, byPath: make(map[string]*archiveEntry)}, nil
+}
+
+// entryName turns a path given to the FS into the slash separated name in the archive
+func entryName(name string) string {
+	return strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "./")
+}
+
+func (a *ArchiveFS) MkdirAll(dir string, perm fs.FileMode) error {
+	name := entryName(dir)
+	if name == "." || name == "" {
+		return nil
+	}
+	if entry, ok := a.byPath[name]; ok {
+		if !entry.isDir {
+			return fmt.Errorf("%s is a file", name)
+		}
+		return nil
+	}
+	if err := a.MkdirAll(path.Dir(name), perm); err != nil {
+		return err
+	}
+	a.add(&archiveEntry{name: name, isDir: true, mode: perm, modTime: time.Now()})
+	return nil
+}
+
+func (a *ArchiveFS) WriteFile(file string, data []byte, perm fs.FileMode) error {
+	name := entryName(file)
+	if entry, ok := a.byPath[name]; ok {
+		if entry.isDir {
+			return fmt.Errorf("%s is a directory", name)
+		}
+		entry.data = data
+		return nil
+	}
+	a.add(&archiveEntry{name: name, data: data, mode: perm &^ 0022, modTime: time.Now()})
+	return nil
+}
+
+func (a *ArchiveFS) add(entry *archiveEntry) {
+	a.entries = append(a.entries, entry)
+	a.byPath[entry.name] = entry
+}
+
+// Stat only knows about entries created so far, the archive starts empty
+func (a *ArchiveFS) Stat(name string) (fs.FileInfo, error) {
+	entry, ok := a.byPath[entryName(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return entry, nil
+}
+
+func (a *ArchiveFS) Chmod(name string, mode fs.FileMode) error {
+	entry, ok := a.byPath[entryName(name)]
+	if !ok {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrNotExist}
+	}
+	entry.mode = mode.Perm()
+	return nil
+}
+
+func (a *ArchiveFS) Chtimes(name string, atime, mtime time.Time) error {
+	entry, ok := a.byPath[entryName(name)]
+	if !ok {
+		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrNotExist}
+	}
+	if !mtime.IsZero() {
+		entry.modTime = mtime
+	}
+	return nil
+}
+
+func (a *ArchiveFS) RemoveAll(dir string) error {
+	name := entryName(dir)
+	a.entries = slices.DeleteFunc(a.entries, func(entry *archiveEntry) bool {
+		if entry.name == name || strings.HasPrefix(entry.name, name+"/") {
+			delete(a.byPath, entry.name)
+			return true
+		}
+		return false
+	})
+	return nil
+}
+
+// Close writes the archive, entries are stored in the order they were created
+func (a *ArchiveFS) Close() error {
+	if a.format == "zip" {
+		return a.writeZip()
+	}
+	if a.format == "tar" {
+		return a.writeTar(a.w)
+	}
+
+	gz := gzip.NewWriter(a.w)
+	if err := a.writeTar(gz); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// archiveEntry is its own fs.FileInfo for Stat
+func (e *archiveEntry) Name() string       { return path.Base(e.name) }
+func (e *archiveEntry) Size() int64        { return int64(len(e.data)) }
+func (e *archiveEntry) ModTime() time.Time { return e.modTime }
+func (e *archiveEntry) IsDir() bool        { return e.isDir }
+func (e *archiveEntry) Sys() any           { return nil }
+func (e *archiveEntry) Mode() fs.FileMode {
+	if e.isDir {
+		return fs.ModeDir | e.mode
+	}
+	return e.mode
+}
+
+func (a *ArchiveFS) writeZip() error {
+	zw := zip.NewWriter(a.w)
+	for _, entry := range a.entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: entry.modTime}
+		if entry.isDir {
+			header.Name += "/"
+			header.Method = zip.Store
+			header.SetMode(fs.ModeDir | entry.mode)
+		} else {
+			header.SetMode(entry.mode)
+		}
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("error adding %s to archive: %v", entry.name, err)
+		}
+		if _, err := fw.Write(entry.data); err != nil {
+			return fmt.Errorf("error adding %s to archive: %v", entry.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+func (a *ArchiveFS) writeTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for _, entry := range a.entries {
+		header := &tar.Header{Name: entry.name, Mode: int64(entry.mode), ModTime: entry.modTime, Typeflag: tar.TypeReg, Size: int64(len(entry.data))}
+		if entry.isDir {
+			header.Name += "/"
+			header.Typeflag = tar.TypeDir
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("error adding %s to archive: %v", entry.name, err)
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			return fmt.Errorf("error adding %s to archive: %v", entry.name, err)
+		}
+	}
+	return tw.Close()
+}
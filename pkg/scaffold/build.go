@@ -0,0 +1,379 @@
+package scaffold
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// BuildOptions controls how Build writes a parsed structure to disk
+type BuildOptions struct {
+	DryRun    bool                          // only print what would be created
+	Force     bool                          // overwrite files that already exist
+	Quiet     bool                          // only print warnings and errors
+	KeepGoing bool                          // continue past failed entries and report them all at the end
+	Templates map[string]*template.Template // default file bodies keyed by extension, see LoadTemplates
+	Log       io.Writer                     // progress and warning output, os.Stderr when nil
+	Verbose   bool                          // prefix progress lines with [n/total]
+	ModTimes  bool                          // set recorded modification times on created entries
+	FS        FS                            // filesystem to create the structure in, OSFS when nil
+	// CopySources fills files of a scanned tree with the content of the scanned file,
+	// turning Scan and Build into a copy of a template directory
+	CopySources bool
+	// Vars replaces {{KEY}} placeholders in written file contents and in the names of
+	// the tree, Build renames the nodes so a later Prune sees the created names
+	Vars map[string]string
+	// MaxDepth is how many levels below the output directory are created, 0 creates
+	// everything. deeper entries are left out, so a large structure can be laid out a
+	// few levels at a time
+	MaxDepth int
+	// Placeholder is the name of an empty file, like .gitkeep, added to every new
+	// directory that has no entries in the tree, so git keeps the directory. it is
+	// added to the tree as well, so a later Prune keeps it. "" adds nothing
+	Placeholder string
+
+	progress  *progress
+	created   *[]string // paths created so far, returned by Build
+	rootDepth int       // depth of the root passed to Build, MaxDepth counts from there
+}
+
+// progress counts the entries Build has handled so far out of total
+type progress struct {
+	done, total int
+}
+
+// templateData is passed to file templates when they are rendered
+type templateData struct {
+	Name string // file name
+	Dir  string // directory the file is created in
+}
+
+// Build creates the children of root under basePath and returns the paths it created
+// in order, directories before their contents. directories that already existed and
+// existing files that were skipped are not included, and with opts.DryRun the paths
+// that would have been created are returned. the whole tree is checked first and
+// nothing is created when a name would escape basePath. with opts.KeepGoing the paths
+// created before and after a failure are returned along with the error
+func Build(basePath string, root *Node, opts BuildOptions) ([]string, error) {
+	if len(opts.Vars) > 0 {
+		root.Walk(func(n *Node) error {
+			n.name = opts.replaceVars(n.name)
+			return nil
+		})
+	}
+	if opts.Placeholder != "" {
+		addPlaceholders(basePath, root, opts, true)
+	}
+	if err := validateNames(basePath, root); err != nil {
+		return nil, err
+	}
+	opts.rootDepth = root.depth
+	if opts.Verbose {
+		total := 0
+		root.Walk(func(n *Node) error {
+			if n == root {
+				return nil
+			}
+			total++
+			if opts.belowMaxDepth(n) {
+				return SkipDir
+			}
+			return nil
+		})
+		opts.progress = &progress{total: total}
+	}
+	var created []string
+	opts.created = &created
+	err := createFromTree(basePath, root, opts)
+	return created, err
+}
+
+// addPlaceholders adds an opts.Placeholder file to every directory below node that has
+// no entries. with checkDisk directories that already exist below dir only keep a
+// placeholder from an earlier run, others may hold files that are not part of the tree
+func addPlaceholders(dir string, node *Node, opts BuildOptions, checkDisk bool) {
+	for _, child := range node.children {
+		if !child.isDir {
+			continue
+		}
+		fullPath := filepath.Join(dir, child.name)
+		if len(child.children) > 0 {
+			addPlaceholders(fullPath, child, opts, checkDisk)
+			continue
+		}
+		if checkDisk {
+			_, dirErr := opts.fs().Stat(fullPath)
+			_, fileErr := opts.fs().Stat(filepath.Join(fullPath, opts.Placeholder))
+			if dirErr == nil && fileErr != nil {
+				continue
+			}
+		}
+		child.children = []*Node{{name: opts.Placeholder, parent: child, depth: child.depth + 1}}
+	}
+}
+
+// validateNames rejects names that are not a single path element, like "..", "a/b"
+// or an absolute path, and any entry whose resolved path is not inside basePath
+func validateNames(basePath string, root *Node) error {
+	base, err := filepath.Abs(basePath)
+	if err != nil {
+		return fmt.Errorf("error resolving output directory %s: %v", basePath, err)
+	}
+
+	var walk func(dir string, node *Node) error
+	walk = func(dir string, node *Node) error {
+		for _, child := range node.children {
+			name := child.name
+			if !validName(name) {
+				return fmt.Errorf("invalid name %q in %s: names must be a single file or directory name", name, dir)
+			}
+			fullPath := filepath.Join(dir, name)
+			if rel, err := filepath.Rel(base, fullPath); err != nil || !filepath.IsLocal(rel) {
+				return fmt.Errorf("invalid name %q: %s is outside %s", name, fullPath, base)
+			}
+			if err := walk(fullPath, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(base, root)
+}
+
+// validName reports whether name is a single path element that stays inside its directory
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsRune(name, '/') &&
+		!strings.ContainsRune(name, filepath.Separator) && filepath.VolumeName(name) == ""
+}
+
+// createFromTree creates the children of node under basePath. existing files are
+// skipped unless opts.Force is set, and opts.DryRun only prints what would be created.
+// with opts.KeepGoing failures are collected and returned together at the end
+func createFromTree(basePath string, node *Node, opts BuildOptions) error {
+	if opts.belowMaxDepth(node) {
+		return nil
+	}
+	var errs []error
+	for _, child := range node.children {
+		if err := createNode(filepath.Join(basePath, child.name), child, opts); err != nil {
+			if !opts.KeepGoing {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// createNode creates a single entry at fullPath, and the children of a directory
+func createNode(fullPath string, child *Node, opts BuildOptions) error {
+	logPrefix := ""
+	if opts.DryRun {
+		logPrefix = "[dry-run] "
+	}
+	if opts.progress != nil {
+		opts.progress.done++
+		logPrefix = fmt.Sprintf("[%d/%d] %s", opts.progress.done, opts.progress.total, logPrefix)
+	}
+
+	if child.isDir {
+		opts.logf("%sCreating directory: %s\n", logPrefix, fullPath)
+		_, statErr := opts.fs().Stat(fullPath)
+		if err := opts.fs().MkdirAll(fullPath, 0755); err != nil {
+			return fmt.Errorf("error creating directory %s: %v", fullPath, err)
+		}
+		if statErr != nil {
+			opts.record(fullPath)
+		}
+		if err := createFromTree(fullPath, child, opts); err != nil {
+			return err
+		}
+		// applied after the children so a read-only directory can still be filled,
+		// and so creating the children does not change the restored time again
+		if err := applyModTime(fullPath, child, opts); err != nil {
+			return err
+		}
+		return applyPerm(fullPath, child, opts)
+	}
+
+	if !opts.Force {
+		if _, err := opts.fs().Stat(fullPath); err == nil {
+			fmt.Fprintf(opts.log(), "%sskipping existing file: %s\n", logPrefix, fullPath)
+			return nil
+		}
+	}
+
+	opts.logf("%sCreating file: %s\n", logPrefix, fullPath)
+	if err := opts.fs().MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("error creating parent directories for %s: %v", fullPath, err)
+	}
+	content, err := fileContent(child, filepath.Dir(fullPath), opts)
+	if err != nil {
+		return err
+	}
+	content = opts.replaceVars(content)
+	if err := opts.fs().WriteFile(fullPath, []byte(content), 0666); err != nil {
+		return fmt.Errorf("error creating file %s: %v", fullPath, err)
+	}
+	opts.record(fullPath)
+	if err := applyModTime(fullPath, child, opts); err != nil {
+		return err
+	}
+	return applyPerm(fullPath, child, opts)
+}
+
+// belowMaxDepth reports whether the children of node are deeper than opts.MaxDepth
+func (opts BuildOptions) belowMaxDepth(node *Node) bool {
+	return opts.MaxDepth > 0 && node.depth-opts.rootDepth >= opts.MaxDepth
+}
+
+// Prune removes everything in existing, a Scan of basePath, that is not part of root,
+// so basePath ends up matching the structure exactly. removals are always logged, and
+// with opts.DryRun nothing is removed. entries Scan skipped, like .git, are kept
+func Prune(basePath string, root, existing *Node, opts BuildOptions) error {
+	logPrefix := ""
+	if opts.DryRun {
+		logPrefix = "[dry-run] "
+	}
+
+	var errs []error
+	for _, change := range Diff(root, existing) {
+		if !change.Added {
+			continue
+		}
+		fullPath := filepath.Join(basePath, filepath.FromSlash(strings.TrimSuffix(change.Path, "/")))
+		fmt.Fprintf(opts.log(), "%sRemoving: %s\n", logPrefix, fullPath)
+		if err := opts.fs().RemoveAll(fullPath); err != nil {
+			err = fmt.Errorf("error removing %s: %v", fullPath, err)
+			if !opts.KeepGoing {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// log returns the writer progress and warnings go to
+func (opts BuildOptions) log() io.Writer {
+	if opts.Log == nil {
+		return os.Stderr
+	}
+	return opts.Log
+}
+
+// replaceVars replaces every {{KEY}} in s with the value of KEY in opts.Vars
+func (opts BuildOptions) replaceVars(s string) string {
+	if len(opts.Vars) == 0 || !strings.Contains(s, "{{") {
+		return s
+	}
+	pairs := make([]string, 0, 2*len(opts.Vars))
+	for key, value := range opts.Vars {
+		pairs = append(pairs, "{{"+key+"}}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// fs returns the filesystem to write to, changes are dropped in a dry run
+func (opts BuildOptions) fs() FS {
+	fsys := opts.FS
+	if fsys == nil {
+		fsys = OSFS
+	}
+	if opts.DryRun {
+		return dryRunFS{fsys}
+	}
+	return fsys
+}
+
+// record adds a created path to the list Build returns
+func (opts BuildOptions) record(fullPath string) {
+	if opts.created != nil {
+		*opts.created = append(*opts.created, fullPath)
+	}
+}
+
+// logf prints a progress line unless quiet is set
+func (opts BuildOptions) logf(format string, args ...any) {
+	if !opts.Quiet {
+		fmt.Fprintf(opts.log(), format, args...)
+	}
+}
+
+// applyPerm sets the permissions given in the input on the created path
+func applyPerm(fullPath string, node *Node, opts BuildOptions) error {
+	if !node.hasPerm {
+		return nil
+	}
+	if err := opts.fs().Chmod(fullPath, node.perm); err != nil {
+		return fmt.Errorf("error setting permissions on %s: %v", fullPath, err)
+	}
+	return nil
+}
+
+// applyModTime sets the modification time recorded for node on the created path
+func applyModTime(fullPath string, node *Node, opts BuildOptions) error {
+	if !opts.ModTimes || node.modTime.IsZero() {
+		return nil
+	}
+	if err := opts.fs().Chtimes(fullPath, time.Time{}, node.modTime); err != nil {
+		return fmt.Errorf("error setting modification time on %s: %v", fullPath, err)
+	}
+	return nil
+}
+
+// LoadTemplates reads every *.tmpl file in dir. the template key is the file name
+// without .tmpl, e.g. go.tmpl is used for .go files and makefile.tmpl for Makefile
+func LoadTemplates(dir string) (map[string]*template.Template, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*template.Template)
+	for _, path := range paths {
+		tmpl, err := template.ParseFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing template %s: %w", path, err)
+		}
+		key := strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".tmpl"))
+		templates[key] = tmpl
+	}
+	return templates, nil
+}
+
+// fileContent returns what should be written to a file node. inline content wins,
+// then the scanned source file with opts.CopySources, otherwise the template matching
+// the file's extension (or name when it has none) is rendered
+func fileContent(node *Node, dir string, opts BuildOptions) (string, error) {
+	if node.content == "" && opts.CopySources && node.source != "" {
+		data, err := os.ReadFile(node.source)
+		if err != nil {
+			return "", fmt.Errorf("error reading %s: %v", node.source, err)
+		}
+		return string(data), nil
+	}
+	if node.content != "" || opts.Templates == nil {
+		return node.content, nil
+	}
+
+	key := strings.TrimPrefix(filepath.Ext(node.name), ".")
+	if key == "" {
+		key = node.name
+	}
+	tmpl, ok := opts.Templates[strings.ToLower(key)]
+	if !ok {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, templateData{Name: node.name, Dir: dir}); err != nil {
+		return "", fmt.Errorf("error rendering template for %s: %v", filepath.Join(dir, node.name), err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,222 @@
+package scaffold
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memFS is an FS that keeps everything in a map, so Build can be tested without
+// touching disk. paths are stored slash separated as Build passes them
+type memFS struct {
+	files  map[string]*memFile
+	writes int // calls that changed something
+}
+
+type memFile struct {
+	isDir bool
+	data  []byte
+	mode  fs.FileMode
+}
+
+func newMemFS() *memFS {
+	return &memFS{files: make(map[string]*memFile)}
+}
+
+func (m *memFS) MkdirAll(name string, perm fs.FileMode) error {
+	m.writes++
+	for dir := filepath.ToSlash(name); dir != "/" && dir != "."; dir = path.Dir(dir) {
+		if _, ok := m.files[dir]; !ok {
+			m.files[dir] = &memFile{isDir: true, mode: fs.ModeDir | perm}
+		}
+	}
+	return nil
+}
+
+func (m *memFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.writes++
+	m.files[filepath.ToSlash(name)] = &memFile{data: data, mode: perm}
+	return nil
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	file, ok := m.files[filepath.ToSlash(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return memInfo{name: path.Base(name), file: file}, nil
+}
+
+func (m *memFS) Chmod(name string, mode fs.FileMode) error {
+	m.writes++
+	file, ok := m.files[filepath.ToSlash(name)]
+	if !ok {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrNotExist}
+	}
+	file.mode = file.mode&fs.ModeType | mode
+	return nil
+}
+
+func (m *memFS) Chtimes(string, time.Time, time.Time) error {
+	m.writes++
+	return nil
+}
+
+func (m *memFS) RemoveAll(name string) error {
+	m.writes++
+	name = filepath.ToSlash(name)
+	for key := range m.files {
+		if key == name || strings.HasPrefix(key, name+"/") {
+			delete(m.files, key)
+		}
+	}
+	return nil
+}
+
+// paths returns every stored path below dir, sorted
+func (m *memFS) paths(dir string) []string {
+	var out []string
+	for key := range m.files {
+		if strings.HasPrefix(key, dir+"/") {
+			out = append(out, key)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+type memInfo struct {
+	name string
+	file *memFile
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return int64(len(i.file.data)) }
+func (i memInfo) Mode() fs.FileMode  { return i.file.mode }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return i.file.isDir }
+func (i memInfo) Sys() any           { return nil }
+
+// mustParse parses a tree written in a test
+func mustParse(t *testing.T, input string) *Node {
+	t.Helper()
+	root, err := Parse(strings.NewReader(input), ParseOptions{Log: io.Discard})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return root
+}
+
+const buildInput = `app/
+├── cmd/
+│   └── server/
+│       └── main.go
+├── docs/
+└── README.md
+`
+
+func TestBuildCreatesPaths(t *testing.T) {
+	mem := newMemFS()
+	created, err := Build("/out", mustParse(t, buildInput), BuildOptions{FS: mem, Log: io.Discard})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	want := []string{"/out/app", "/out/app/cmd", "/out/app/cmd/server", "/out/app/cmd/server/main.go", "/out/app/docs", "/out/app/README.md"}
+	if !slices.Equal(created, want) {
+		t.Errorf("created = %q, want %q", created, want)
+	}
+	slices.Sort(want)
+	if got := mem.paths("/out"); !slices.Equal(got, want) {
+		t.Errorf("fs holds %q, want %q", got, want)
+	}
+	if info, err := mem.Stat("/out/app/docs"); err != nil || !info.IsDir() {
+		t.Errorf("docs is not a directory: %v", err)
+	}
+}
+
+func TestBuildDryRunWritesNothing(t *testing.T) {
+	mem := newMemFS()
+	created, err := Build("/out", mustParse(t, buildInput), BuildOptions{FS: mem, DryRun: true, Log: io.Discard})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if mem.writes != 0 || len(mem.files) != 0 {
+		t.Errorf("dry run made %d writes, fs holds %q", mem.writes, mem.paths(""))
+	}
+	if len(created) != 6 {
+		t.Errorf("dry run reported %d paths, want 6: %q", len(created), created)
+	}
+}
+
+func TestBuildForce(t *testing.T) {
+	for _, force := range []bool{false, true} {
+		mem := newMemFS()
+		mem.MkdirAll("/out/app", 0755)
+		mem.WriteFile("/out/app/README.md", []byte("keep me"), 0644)
+
+		created, err := Build("/out", mustParse(t, "app/\n├── README.md\n└── main.go\n"), BuildOptions{FS: mem, Force: force, Log: io.Discard})
+		if err != nil {
+			t.Fatalf("Build: %v", err)
+		}
+
+		readme := string(mem.files["/out/app/README.md"].data)
+		want := []string{"/out/app/main.go"}
+		if force {
+			want = []string{"/out/app/README.md", "/out/app/main.go"}
+			if readme != "" {
+				t.Errorf("with Force README.md holds %q, want it overwritten", readme)
+			}
+		} else if readme != "keep me" {
+			t.Errorf("without Force README.md holds %q, want it skipped", readme)
+		}
+		if !slices.Equal(created, want) {
+			t.Errorf("Force %v: created = %q, want %q", force, created, want)
+		}
+	}
+}
+
+func TestBuildPlaceholder(t *testing.T) {
+	mem := newMemFS()
+	mem.MkdirAll("/out/app/existing", 0755)
+	root := mustParse(t, "app/\n├── docs/\n├── existing/\n├── nested/\n│   └── deep/\n└── src/\n    └── main.go\n")
+
+	if _, err := Build("/out", root, BuildOptions{FS: mem, Placeholder: ".gitkeep", Log: io.Discard}); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	for _, want := range []string{"/out/app/docs/.gitkeep", "/out/app/nested/deep/.gitkeep"} {
+		if _, ok := mem.files[want]; !ok {
+			t.Errorf("%s was not created", want)
+		}
+	}
+	for _, unwanted := range []string{"/out/app/nested/.gitkeep", "/out/app/src/.gitkeep", "/out/app/existing/.gitkeep"} {
+		if _, ok := mem.files[unwanted]; ok {
+			t.Errorf("%s was created", unwanted)
+		}
+	}
+	if _, ok := root.Find("app/docs/.gitkeep"); !ok {
+		t.Error("placeholder was not added to the tree, Prune would remove it")
+	}
+}
+
+func TestBuildMaxDepth(t *testing.T) {
+	mem := newMemFS()
+	created, err := Build("/out", mustParse(t, buildInput), BuildOptions{FS: mem, MaxDepth: 2, Log: io.Discard})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	want := []string{"/out/app", "/out/app/cmd", "/out/app/docs", "/out/app/README.md"}
+	if !slices.Equal(created, want) {
+		t.Errorf("created = %q, want %q", created, want)
+	}
+	if _, ok := mem.files["/out/app/cmd/server"]; ok {
+		t.Error("cmd/server is below MaxDepth but was created")
+	}
+}
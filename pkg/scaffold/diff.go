@@ -0,0 +1,56 @@
+package scaffold
+
+import "path"
+
+// DiffEntry is a path that exists in only one of two trees, or a file whose contents differ
+type DiffEntry struct {
+	Path    string // slash separated path below the roots, directories end in /
+	Added   bool   // the path is only in the second tree, otherwise only in the first
+	Changed bool   // the file is in both trees but its recorded hashes differ
+}
+
+// Diff compares the entries below want and have, the roots themselves are not
+// compared. entries match by name and kind, so a file in one tree and a directory
+// of the same name in the other are reported on both sides. when a directory is
+// missing on one side only the directory is reported, not everything below it.
+// files that have a hash in both trees, see ScanOptions.WithHash, are also compared
+// by content
+func Diff(want, have *Node) []DiffEntry {
+	return diffChildren("", want, have, nil)
+}
+
+func diffChildren(dir string, want, have *Node, out []DiffEntry) []DiffEntry {
+	for _, child := range want.children {
+		if other := findEntry(have, child); other == nil {
+			out = append(out, DiffEntry{Path: diffPath(dir, child)})
+		} else if child.isDir {
+			out = diffChildren(path.Join(dir, child.name), child, other, out)
+		} else if child.hash != "" && other.hash != "" && child.hash != other.hash {
+			out = append(out, DiffEntry{Path: diffPath(dir, child), Changed: true})
+		}
+	}
+	for _, child := range have.children {
+		if findEntry(want, child) == nil {
+			out = append(out, DiffEntry{Path: diffPath(dir, child), Added: true})
+		}
+	}
+	return out
+}
+
+// findEntry returns the child of parent with the same name and kind as node, or nil
+func findEntry(parent, node *Node) *Node {
+	for _, child := range parent.children {
+		if child.name == node.name && child.isDir == node.isDir {
+			return child
+		}
+	}
+	return nil
+}
+
+func diffPath(dir string, node *Node) string {
+	p := path.Join(dir, node.name)
+	if node.isDir {
+		p += "/"
+	}
+	return p
+}
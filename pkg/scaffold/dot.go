@@ -0,0 +1,40 @@
+package scaffold
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeDOT writes root as a Graphviz digraph. directories are boxes, files are plain
+// text, and every node gets an id in walk order so equal names do not collide
+func writeDOT(w io.Writer, root *Node) error {
+	ids := make(map[*Node]string)
+	var sb strings.Builder
+	sb.WriteString("digraph tree {\n\trankdir=LR;\n\tnode [fontname=\"monospace\"];\n")
+	root.Walk(func(n *Node) error {
+		id := fmt.Sprintf("n%d", len(ids))
+		ids[n] = id
+
+		shape := "plaintext"
+		label := n.name
+		if n.isDir {
+			shape = "box"
+			label += "/"
+		}
+		fmt.Fprintf(&sb, "\t%s [label=%s, shape=%s];\n", id, dotQuote(label), shape)
+		if n.parent != nil {
+			fmt.Fprintf(&sb, "\t%s -> %s;\n", ids[n.parent], id)
+		}
+		return nil
+	})
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// dotQuote returns s as a double quoted DOT string
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,44 @@
+package scaffold
+
+import (
+	"io/fs"
+	"os"
+	"time"
+)
+
+// FS is the filesystem Build and Prune write to. OSFS is the real filesystem, tests
+// and other callers can pass their own, e.g. one that keeps everything in memory
+type FS interface {
+	MkdirAll(path string, perm fs.FileMode) error
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	Stat(name string) (fs.FileInfo, error)
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+	RemoveAll(path string) error
+}
+
+// OSFS is the FS backed by the os package
+var OSFS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+func (osFS) Stat(name string) (fs.FileInfo, error)             { return os.Stat(name) }
+func (osFS) Chmod(name string, mode fs.FileMode) error         { return os.Chmod(name, mode) }
+func (osFS) Chtimes(name string, atime, mtime time.Time) error { return os.Chtimes(name, atime, mtime) }
+func (osFS) RemoveAll(path string) error                       { return os.RemoveAll(path) }
+
+// dryRunFS reads from the wrapped FS and ignores every change, it is what
+// BuildOptions.DryRun writes to so a dry run takes the same path as a real one
+type dryRunFS struct {
+	FS
+}
+
+func (dryRunFS) MkdirAll(string, fs.FileMode) error          { return nil }
+func (dryRunFS) WriteFile(string, []byte, fs.FileMode) error { return nil }
+func (dryRunFS) Chmod(string, fs.FileMode) error             { return nil }
+func (dryRunFS) Chtimes(string, time.Time, time.Time) error  { return nil }
+func (dryRunFS) RemoveAll(string) error                      { return nil }
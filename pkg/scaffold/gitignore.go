@@ -0,0 +1,92 @@
+package scaffold
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// gitignoreRule is a single pattern line from a .gitignore file
+type gitignoreRule struct {
+	pattern  string
+	negate   bool // pattern started with !
+	dirOnly  bool // pattern ended with /
+	anchored bool // pattern contains a / and is matched against the full relative path
+}
+
+// gitignore holds the rules of a .gitignore file in the order they were declared
+type gitignore struct {
+	rules []gitignoreRule
+}
+
+// loadGitignore parses the .gitignore file at filename. a missing file gives an empty rule set
+func loadGitignore(filename string) (*gitignore, error) {
+	g := &gitignore{}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return g, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule gitignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		// a leading **/ matches in any directory, which is the same as an unanchored pattern
+		line = strings.TrimPrefix(line, "**/")
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		rule.pattern = line
+		g.rules = append(g.rules, rule)
+	}
+
+	return g, scanner.Err()
+}
+
+// match reports whether relPath, slash separated and relative to the .gitignore
+// location, is ignored. later rules override earlier ones
+func (g *gitignore) match(relPath string, isDir bool) bool {
+	ignored := false
+	name := path.Base(relPath)
+
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		target := name
+		if rule.anchored {
+			target = relPath
+		}
+
+		if matched, err := path.Match(rule.pattern, target); err == nil && matched {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
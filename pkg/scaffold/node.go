@@ -0,0 +1,398 @@
+// Package scaffold turns project structure descriptions into directories and files,
+// and scans existing directories back into the same tree form.
+package scaffold
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMaxNesting is how many levels deep Parse and Scan go before giving up with an
+// error when ParseOptions.MaxNesting or ScanOptions.MaxNesting is not set
+const DefaultMaxNesting = 256
+
+// ErrTooDeep is wrapped by the errors Parse and Scan return for entries past the nesting limit
+var ErrTooDeep = errors.New("nested too deep")
+
+// nestingLimit returns the nesting limit in effect, a negative limit means unlimited
+func nestingLimit(limit int) int {
+	if limit == 0 {
+		return DefaultMaxNesting
+	}
+	return limit
+}
+
+// tooDeep reports whether depth is past limit, see nestingLimit
+func tooDeep(depth, limit int) bool {
+	limit = nestingLimit(limit)
+	return limit > 0 && depth > limit
+}
+
+// SkipDir can be returned from a Walk callback to skip the children of the current node
+var SkipDir = errors.New("skip this directory")
+
+// Node is a directory or file in a project structure tree
+type Node struct {
+	name       string
+	isDir      bool
+	children   []*Node
+	parent     *Node
+	depth      int
+	truncated  bool   // directory has entries below the scan depth limit
+	denied     bool   // directory could not be read by Scan, its entries are unknown
+	content    string // body written to a file node on creation
+	linkTarget string // target of a symlink that was not followed
+	source     string // path of the scanned file, copied with BuildOptions.CopySources
+	size       int64  // file size or directory total in bytes, -1 when unknown
+	lines      int64  // line count of a text file or directory total, -1 for binary or unreadable files
+	hash       string // hex SHA-256 of a scanned file, empty when not recorded
+	perm       os.FileMode
+	hasPerm    bool      // perm was given in the input or recorded by Scan and is applied after creation
+	modTime    time.Time // modification time recorded by Scan or read from JSON or YAML input
+	line       int       // input line the entry was declared on, 0 when unknown
+	dotRoot    bool      // Parse dropped a "." top directory from this root, see StripRootDir
+
+	// comments from a parsed input, with their # or // marker. headComment holds the
+	// comment lines right above the entry and lineComment the comment after its name
+	headComment []string
+	lineComment string
+}
+
+// Name returns the base name of the node
+func (n *Node) Name() string { return n.name }
+
+// IsDir reports whether the node is a directory
+func (n *Node) IsDir() bool { return n.isDir }
+
+// Children returns the direct children of the node
+func (n *Node) Children() []*Node { return n.children }
+
+// Parent returns the parent node, nil for the root
+func (n *Node) Parent() *Node { return n.parent }
+
+// Depth returns the nesting level of the node, the root is 0 and its children are 1
+func (n *Node) Depth() int { return n.depth }
+
+// Content returns the body that is written when the file is created
+func (n *Node) Content() string { return n.content }
+
+// ModTime returns the recorded modification time, the zero time when there is none
+func (n *Node) ModTime() time.Time { return n.modTime }
+
+// Line returns the input line the entry was declared on, 0 for scanned trees and JSON
+func (n *Node) Line() int { return n.line }
+
+// Hash returns the hex SHA-256 of the file recorded by Scan or read from JSON, or ""
+func (n *Node) Hash() string { return n.hash }
+
+// Find returns the node at the slash separated path below n, like "src/main.go".
+// an empty path or "." is n itself, and false is returned when a segment is missing
+func (n *Node) Find(path string) (*Node, bool) {
+	path = strings.Trim(path, "/")
+	if path == "" || path == "." {
+		return n, true
+	}
+
+	node := n
+	for _, segment := range strings.Split(path, "/") {
+		i := slices.IndexFunc(node.children, func(child *Node) bool { return child.name == segment })
+		if i < 0 {
+			return nil, false
+		}
+		node = node.children[i]
+	}
+	return node, true
+}
+
+// Walk calls fn for n and every node below it in depth-first pre-order: a node is
+// visited before its children and children in the order they are stored. when fn
+// returns SkipDir the children of that node are skipped and the walk continues with
+// its next sibling. any other error stops the walk and is returned by Walk
+func (n *Node) Walk(fn func(*Node) error) error {
+	if err := fn(n); err != nil {
+		if errors.Is(err, SkipDir) {
+			return nil
+		}
+		return err
+	}
+	for _, child := range n.children {
+		if err := child.Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRootDir makes root hold a single directory called name. when root has exactly one
+// top level directory it is renamed to name, otherwise all top level entries are moved
+// into a new directory called name
+func SetRootDir(root *Node, name string) {
+	if len(root.children) == 1 && root.children[0].isDir {
+		root.children[0].name = name
+		return
+	}
+
+	root.children = []*Node{{name: name, isDir: true, children: root.children}}
+	root.Recompute()
+}
+
+// StripRootDir replaces the children of root with the entries of its single top level
+// directory, so "myapp/" and everything below it is created straight into the output
+// instead of in a myapp directory. it reports false and leaves root alone when the
+// top level is not exactly one directory. a "." top directory was already dropped by
+// Parse, so for those StripRootDir reports true and changes nothing
+func StripRootDir(root *Node) bool {
+	if root.dotRoot {
+		return true
+	}
+	if len(root.children) != 1 || !root.children[0].isDir {
+		return false
+	}
+
+	root.children = root.children[0].children
+	root.Recompute()
+	return true
+}
+
+// OnlyDirs removes every file and symlink below root, leaving the directory skeleton
+// like tree -d does. directory sizes and line counts still include the removed files
+func OnlyDirs(root *Node) {
+	root.children = slices.DeleteFunc(root.children, func(n *Node) bool { return !n.isDir })
+	for _, child := range root.children {
+		OnlyDirs(child)
+	}
+}
+
+// OnlyFiles removes the directories below root that hold no files at any depth. the
+// directories on the way to a file are kept so a tree drawing stays readable, the flat
+// format without FlatDirs lists the files alone. truncated and unreadable directories
+// are kept since their files are not known
+func OnlyFiles(root *Node) {
+	root.children = slices.DeleteFunc(root.children, func(n *Node) bool {
+		if !n.isDir || n.truncated || n.denied {
+			return false
+		}
+		OnlyFiles(n)
+		return len(n.children) == 0
+	})
+}
+
+// NormalizeNames rewrites every name below root to the Unicode normalization form
+// "nfc" or "nfd", so a name typed in composed form in an input and the same name read
+// back decomposed from a filesystem like the one on macOS compare as equal
+func NormalizeNames(root *Node, form string) error {
+	var f norm.Form
+	switch strings.ToLower(form) {
+	case "nfc":
+		f = norm.NFC
+	case "nfd":
+		f = norm.NFD
+	default:
+		return fmt.Errorf("unknown normalization form %q, use nfc or nfd", form)
+	}
+	return root.Walk(func(n *Node) error {
+		n.name = f.String(n.name)
+		return nil
+	})
+}
+
+// CopyComments copies the comments of src to dst, and those of every entry below src
+// to the entry with the same path and kind below dst. e.g. from the top directory of a
+// parsed structure file to a Scan of the directory created from it, so printing the
+// scan with PrintOptions.Comments keeps the annotations
+func CopyComments(dst, src *Node) {
+	dst.headComment = slices.Clone(src.headComment)
+	dst.lineComment = src.lineComment
+	for _, child := range src.children {
+		i := slices.IndexFunc(dst.children, func(n *Node) bool { return n.name == child.name && n.isDir == child.isDir })
+		if i >= 0 {
+			CopyComments(dst.children[i], child)
+		}
+	}
+}
+
+// Merge adds the entries below src to dst. directories that exist in both are merged,
+// a file in src replaces a file with the same name in dst, and a file and directory
+// at the same path is an error. src should not be used afterwards
+func Merge(dst, src *Node) error {
+	err := mergeChildren(dst, src, "")
+	dst.Recompute()
+	return err
+}
+
+func mergeChildren(dst, src *Node, dir string) error {
+	for _, child := range src.children {
+		fullPath := path.Join(dir, child.name)
+		i := slices.IndexFunc(dst.children, func(n *Node) bool { return n.name == child.name })
+		if i < 0 {
+			dst.children = append(dst.children, child)
+			continue
+		}
+
+		existing := dst.children[i]
+		switch {
+		case existing.isDir != child.isDir:
+			return fmt.Errorf("%s is a file in one input and a directory in another", fullPath)
+		case child.isDir:
+			if child.hasPerm {
+				existing.perm, existing.hasPerm = child.perm, true
+			}
+			if err := mergeChildren(existing, child, fullPath); err != nil {
+				return err
+			}
+		default:
+			dst.children[i] = child
+		}
+	}
+	return nil
+}
+
+// Recompute sets the parent of every node below n to the node that holds it and the
+// depth of n and every node below it from their position: 0 without a parent and one
+// more than the parent otherwise. Parse, Merge and the functions that move entries
+// call it, so stored depths stay right after a tree was restructured
+func (n *Node) Recompute() {
+	n.depth = 0
+	if n.parent != nil {
+		n.depth = n.parent.depth + 1
+	}
+	for _, child := range n.children {
+		child.parent = n
+		child.Recompute()
+	}
+}
+
+// jsonNode is the exported shape of a Node used for JSON output
+type jsonNode struct {
+	Name       string      `json:"name"`
+	IsDir      bool        `json:"isDir"`
+	Children   []*jsonNode `json:"children,omitempty"`
+	Truncated  bool        `json:"truncated,omitempty"`
+	Denied     bool        `json:"permissionDenied,omitempty"`
+	LinkTarget string      `json:"linkTarget,omitempty"`
+	Size       int64       `json:"size,omitempty"`
+	Lines      int64       `json:"lines,omitempty"`
+	ModTime    *time.Time  `json:"modTime,omitempty"`
+	Mode       string      `json:"mode,omitempty"` // octal permissions like "0755"
+	SHA256     string      `json:"sha256,omitempty"`
+	Content    string      `json:"content,omitempty"`
+}
+
+func (n *Node) toJSONNode() *jsonNode {
+	out := &jsonNode{Name: n.name, IsDir: n.isDir, Truncated: n.truncated, Denied: n.denied, LinkTarget: n.linkTarget, Size: n.size, Lines: n.lines, SHA256: n.hash, Content: n.content}
+	if !n.modTime.IsZero() {
+		out.ModTime = &n.modTime
+	}
+	if n.hasPerm {
+		out.Mode = fmt.Sprintf("%04o", n.perm)
+	}
+	for _, child := range n.children {
+		out.Children = append(out.Children, child.toJSONNode())
+	}
+	return out
+}
+
+// MarshalJSON encodes the node and its children as a nested JSON document
+func (n *Node) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.toJSONNode())
+}
+
+// checkJSONNode collects what in and its children break in JSONSchema. dir is the
+// slash separated path of the directory holding in, "" for the top level object
+func checkJSONNode(in *jsonNode, dir string, problems []error) []error {
+	at := in.Name
+	if dir != "" {
+		at = dir + "/" + in.Name
+	}
+	if in.Name == "" && dir == "" {
+		problems = append(problems, errors.New("missing name on the top level entry"))
+	} else if in.Name == "" {
+		problems = append(problems, fmt.Errorf("missing name on an entry in %s", dir))
+	} else if strings.ContainsAny(in.Name, `/\`) {
+		problems = append(problems, fmt.Errorf("name %q at %s must be a single file or directory name", in.Name, at))
+	}
+	if !in.IsDir && len(in.Children) > 0 {
+		problems = append(problems, fmt.Errorf("children on a file node at %s, set \"isDir\": true for a directory", at))
+	}
+	if in.IsDir && in.Content != "" {
+		problems = append(problems, fmt.Errorf("content on a directory node at %s", at))
+	}
+	if in.Mode != "" && !jsonMode.MatchString(in.Mode) {
+		problems = append(problems, fmt.Errorf("mode %q at %s is not an octal mode like \"0755\"", in.Mode, at))
+	}
+	if in.SHA256 != "" && !jsonHash.MatchString(in.SHA256) {
+		problems = append(problems, fmt.Errorf("sha256 at %s is not 64 lowercase hex digits", at))
+	}
+	for _, child := range in.Children {
+		problems = checkJSONNode(child, at, problems)
+	}
+	return problems
+}
+
+// patterns of the mode and sha256 fields in JSONSchema
+var (
+	jsonMode = regexp.MustCompile(`^0?[0-7]{3}$`)
+	jsonHash = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// fromJSONNode converts a decoded JSON document back into a node below parent
+func fromJSONNode(in *jsonNode, parent *Node, opts ParseOptions) (*Node, error) {
+	if tooDeep(parent.depth+1, opts.MaxNesting) {
+		return nil, fmt.Errorf("%s is %w, the limit is %d levels", in.Name, ErrTooDeep, nestingLimit(opts.MaxNesting))
+	}
+	n := &Node{name: in.Name, isDir: in.IsDir, parent: parent, depth: parent.depth + 1, content: in.Content, hash: in.SHA256}
+	if in.ModTime != nil {
+		n.modTime = *in.ModTime
+	}
+	if perm, err := strconv.ParseUint(in.Mode, 8, 32); err == nil {
+		n.perm, n.hasPerm = os.FileMode(perm).Perm(), true
+	}
+	for _, child := range in.Children {
+		node, err := fromJSONNode(child, n, opts)
+		if err != nil {
+			return nil, err
+		}
+		n.children = append(n.children, node)
+	}
+	return n, nil
+}
+
+// JSONSchema is the JSON Schema of the documents MarshalJSON writes and JSON input is
+// checked against, for editors and other tools that produce structures
+//
+//go:embed schema.json
+var JSONSchema []byte
+
+// parseJSON reads a document written by MarshalJSON. the top level object becomes
+// the only entry below the returned root, like the top level key of a YAML input.
+// the document is checked against JSONSchema first and every mismatch is reported
+func parseJSON(r io.Reader, opts ParseOptions) (*Node, error) {
+	var doc jsonNode
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("error decoding json: %w", err)
+	}
+	if problems := checkJSONNode(&doc, "", nil); len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	root := &Node{name: ".", isDir: true}
+	node, err := fromJSONNode(&doc, root, opts)
+	if err != nil {
+		return nil, err
+	}
+	root.children = []*Node{node}
+	return root, nil
+}
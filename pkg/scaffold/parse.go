@@ -0,0 +1,911 @@
+package scaffold
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// filesWithoutExtensions are lowercase names without a dot that are files, not directories
+var filesWithoutExtensions = map[string]bool{
+	"license":     true,
+	"readme":      true,
+	"makefile":    true,
+	"dockerfile":  true,
+	"procfile":    true,
+	"gemfile":     true,
+	"rakefile":    true,
+	"changelog":   true,
+	"authors":     true,
+	"notice":      true,
+	"vagrantfile": true,
+}
+
+// permAnnotation matches a trailing octal mode like "run.sh (0755)". only the
+// permission bits can be given, setuid, setgid and sticky like (4755) are not supported
+var permAnnotation = regexp.MustCompile(`^(.*?)\s+\((0?[0-7]{3})\)$`)
+
+// summaryLine matches the "N directories, M files" line printed after a tree
+var summaryLine = regexp.MustCompile(`^\d+ director(y|ies), \d+ files?$`)
+
+// promptLine matches a shell prompt pasted along with a tree, like "$ tree" or
+// "me@host:~/app$ tree -a"
+var promptLine = regexp.MustCompile(`^\S*\$( |$)`)
+
+// LineError describes an input line that could not be turned into a node
+type LineError struct {
+	Number int
+	Text   string
+}
+
+// ParseError is returned by Parse when one or more lines could not be parsed
+type ParseError struct {
+	Lines []LineError
+}
+
+func (e *ParseError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d line(s) could not be parsed:", len(e.Lines))
+	for _, line := range e.Lines {
+		fmt.Fprintf(&sb, "\n  line %d: %q", line.Number, line.Text)
+	}
+	return sb.String()
+}
+
+// inputLine is a structure line from an input file along with the fenced block that follows it
+type inputLine struct {
+	text    string
+	number  int // 1-based line number in the input file
+	content *string
+}
+
+// ParseOptions controls how Parse reads a structure description
+type ParseOptions struct {
+	Markdown bool // the input is a Markdown document, only its tree code block is parsed
+	Lenient  bool // the input is prose around a drawn tree, like a chat message, only the tree is parsed
+	YAML     bool // the input is a YAML document of nested mappings instead of a tree
+	JSON     bool // the input is a JSON document as written by MarshalJSON
+
+	// ExplicitDirs reads names without a trailing slash as files, like in the mode 1
+	// tree where every directory has one. it is implied by the "N directories, M files"
+	// line that tree output ends with, hand written input keeps the name heuristic
+	ExplicitDirs bool
+
+	Expand     bool              // expand $VAR and ${VAR} in names from Vars and the environment
+	Vars       map[string]string // variables for Expand, checked before the environment
+	StrictVars bool              // an undefined variable is an error instead of expanding to ""
+
+	FilesWithoutExt []string // extra extensionless names that are files, added to the defaults
+
+	MaxNesting int    // deepest level an entry may be at, DefaultMaxNesting when 0 and unlimited when negative
+	Normalize  string // Unicode normalization form for names, "nfc" or "nfd", see NormalizeNames
+
+	RawNames bool      // take names as written after the connector, keeping leading dashes, box characters and surrounding spaces
+	Log      io.Writer // warnings about trimmed names, os.Stderr when nil
+}
+
+// log returns the writer warnings go to
+func (opts ParseOptions) log() io.Writer {
+	if opts.Log == nil {
+		return os.Stderr
+	}
+	return opts.Log
+}
+
+// expand replaces the variables in name when Expand is set
+func (opts ParseOptions) expand(name string) (string, error) {
+	if !opts.Expand {
+		return name, nil
+	}
+
+	var missing []string
+	expanded := os.Expand(name, func(key string) string {
+		if value, ok := opts.Vars[key]; ok {
+			return value
+		}
+		if value, ok := os.LookupEnv(key); ok {
+			return value
+		}
+		missing = append(missing, key)
+		return ""
+	})
+	if len(missing) > 0 && opts.StrictVars {
+		return "", fmt.Errorf("undefined variable %s in %q", strings.Join(missing, ", "), name)
+	}
+	return expanded, nil
+}
+
+// ParseFile opens filename and parses the structure in it. files ending in .md or
+// .markdown are read as Markdown, files ending in .yaml or .yml as YAML and .json as JSON.
+// a .gz suffix is skipped for this, like in tree.yaml.gz, see Parse for compressed input
+func ParseFile(filename string, opts ParseOptions) (*Node, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch filepath.Ext(strings.TrimSuffix(strings.ToLower(filename), ".gz")) {
+	case ".md", ".markdown":
+		opts.Markdown = true
+	case ".yaml", ".yml":
+		opts.YAML = true
+	case ".json":
+		opts.JSON = true
+	}
+
+	return Parse(file, opts)
+}
+
+// Parse reads a structure description from r and builds the node tree. the returned
+// root is named "." and holds the top level entries of the input. gzip compressed input
+// is recognized by its first bytes and decompressed
+func Parse(r io.Reader, opts ParseOptions) (*Node, error) {
+	r, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parse(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	// tree prints the directory it ran in as ".", its entries are the top level
+	if len(root.children) == 1 && root.children[0].isDir && root.children[0].name == "." {
+		root.children = root.children[0].children
+		root.dotRoot = true
+	}
+	root.Recompute()
+	if err := checkKinds(root, ""); err != nil {
+		return nil, err
+	}
+	if opts.Normalize == "" {
+		return root, nil
+	}
+	if err := NormalizeNames(root, opts.Normalize); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// gzipMagic are the first two bytes of every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress returns a reader of the decompressed data when r holds a gzip stream, and
+// one reading r as it is otherwise
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); !bytes.Equal(magic, gzipMagic) {
+		return br, nil
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("error reading gzip input: %v", err)
+	}
+	return zr, nil
+}
+
+func parse(r io.Reader, opts ParseOptions) (*Node, error) {
+	if opts.YAML {
+		return parseYAML(r, opts)
+	}
+	if opts.JSON {
+		return parseJSON(r, opts)
+	}
+
+	scanner := bufio.NewScanner(r)
+	var rawLines []string
+	for scanner.Scan() {
+		// trim \r and trailing whitespace so files written on Windows give clean names,
+		// with RawNames trailing spaces can be part of a name
+		cutset := " \t\r"
+		if opts.RawNames {
+			cutset = "\r"
+		}
+		rawLines = append(rawLines, strings.TrimRight(scanner.Text(), cutset))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	offset := 0
+	switch {
+	case opts.Lenient:
+		var err error
+		rawLines, offset, err = extractTreeBlock(rawLines)
+		if err != nil {
+			return nil, err
+		}
+	case opts.Markdown:
+		var err error
+		rawLines, offset, err = extractMarkdownBlock(rawLines)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rawLines = cleanPaste(rawLines)
+	lines, err := splitFences(rawLines, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// plain indented lists have no box-drawing characters; use whitespace depth for those
+	indentMode := !hasTreeCharacters(lines)
+	indentUnit := detectIndentUnit(lines)
+	treeWidth, treeColumn := detectTreeWidth(lines)
+	lineDepth := func(line string) (int, string) {
+		if indentMode {
+			return parseIndentedLine(line, indentUnit)
+		}
+		return parseLine(line, treeWidth, treeColumn)
+	}
+
+	// a subtree copied out of a larger tree starts several levels deep, the shallowest
+	// entry is taken as the top level so it is not nested below missing parents
+	baseline := -1
+	for _, input := range lines {
+		if depth, name := lineDepth(input.text); !skipLine(input.text) && name != "" && (baseline < 0 || depth < baseline) {
+			baseline = depth
+		}
+	}
+	baseline = max(baseline, 0)
+
+	var nodes []*Node
+	var badLines []LineError
+	slashed := make(map[*Node]bool) // entries written with a trailing slash
+	root := &Node{name: ".", isDir: true}
+	parents := []*Node{root} // parents[d] is the parent of an entry at depth d
+	var currentDepth int = 0
+
+	var headComment []string // comment lines waiting for the entry below them
+	for _, input := range lines {
+		line := input.text
+		// comment lines on their own are kept for the next entry
+		rest := strings.TrimLeft(line, treeChars)
+		if skipLine(line) {
+			if isComment(rest) {
+				headComment = append(headComment, strings.TrimSpace(rest))
+			}
+			continue
+		}
+		_, lineComment := splitComment(rest)
+
+		// Calculate depth and name
+		depth, name := lineDepth(line)
+		if name != "" {
+			depth -= baseline
+			// the tree characters and spaces trimmed from a name are usually drawing,
+			// but can be part of a name like -flag.txt, so a changed name is reported
+			raw := rawName(line, indentMode)
+			if opts.RawNames {
+				name = raw
+			} else if raw != name {
+				fmt.Fprintf(opts.log(), "warning: line %d: name %q is read as %q\n", input.number, raw, name)
+			}
+		}
+		name, err := opts.expand(name)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", input.number, err)
+		}
+		// an optional trailing (0755) sets the permissions of the entry
+		name, perm, hasPerm := splitPermAnnotation(name)
+
+		// the trailing slash only marks a directory, it is not part of the name.
+		// "/..." is how Print marks a directory cut off by the scan depth limit
+		if strings.HasSuffix(name, "/...") {
+			name = strings.TrimSuffix(name, "...")
+		}
+		hasSlash := strings.HasSuffix(name, "/")
+		name = strings.TrimRight(name, "/")
+		// src/main/App.java is a chain of directories ending in the entry
+		segments := strings.Split(name, "/")
+		if slices.Contains(segments, "") {
+			badLines = append(badLines, LineError{Number: input.number, Text: line})
+			continue
+		}
+		name = segments[len(segments)-1]
+		// a slash wins over the name heuristic, so an empty directory like v1.0/ stays one
+		isDir := hasSlash || opts.isDirName(name)
+
+		// a line may only go one level deeper than the previous entry
+		if depth > currentDepth+1 || (depth > currentDepth && len(nodes) == 0) {
+			return nil, fmt.Errorf("line %d: depth jumps from %d to %d: %q", input.number, currentDepth, depth, line)
+		}
+
+		// Adjust parent based on depth, a deeper line is a child of the previous entry
+		if depth > currentDepth {
+			// an entry with children is a directory even when its name looks like a
+			// file, like "." at the top of tree output or v1.0
+			prev := nodes[len(nodes)-1]
+			if !prev.isDir && prev.content != "" {
+				return nil, fmt.Errorf("line %d: %s has a fenced block and can not have children", input.number, prev.name)
+			}
+			prev.isDir = true
+			parents = append(parents, prev)
+		}
+		parents = parents[:depth+1]
+		currentParent := parents[depth]
+
+		// intermediate directories are shared with earlier lines that created them
+		for _, segment := range segments[:len(segments)-1] {
+			dir := findDir(currentParent, segment)
+			if dir == nil {
+				dir = &Node{name: segment, isDir: true, parent: currentParent, depth: currentParent.depth + 1, line: input.number}
+				slashed[dir] = true
+				currentParent.children = append(currentParent.children, dir)
+				nodes = append(nodes, dir)
+			}
+			currentParent = dir
+		}
+
+		if input.content != nil && isDir {
+			return nil, fmt.Errorf("line %d: fenced block follows directory %s", input.number, name)
+		}
+		if tooDeep(currentParent.depth+1, opts.MaxNesting) {
+			return nil, fmt.Errorf("line %d: %s is %w, the limit is %d levels", input.number, name, ErrTooDeep, nestingLimit(opts.MaxNesting))
+		}
+
+		// a directory declared again is merged with the earlier one instead of duplicated
+		if dir := findDir(currentParent, name); dir != nil && isDir {
+			if hasPerm {
+				dir.perm, dir.hasPerm = perm, true
+			}
+			dir.headComment = append(dir.headComment, headComment...)
+			if dir.lineComment == "" {
+				dir.lineComment = lineComment
+			}
+			headComment = nil
+			if hasSlash {
+				slashed[dir] = true
+			}
+			nodes = append(nodes, dir)
+			currentDepth = depth
+			continue
+		}
+
+		node := &Node{
+			name:    name,
+			isDir:   isDir,
+			parent:  currentParent,
+			depth:   currentParent.depth + 1, // the root is depth 0, like in Scan
+			perm:    perm,
+			hasPerm: hasPerm,
+			line:    input.number,
+
+			headComment: headComment,
+			lineComment: lineComment,
+		}
+		headComment = nil
+
+		if hasSlash {
+			slashed[node] = true
+		}
+
+		if input.content != nil {
+			node.content = *input.content
+		}
+
+		currentParent.children = append(currentParent.children, node)
+		nodes = append(nodes, node)
+		currentDepth = depth
+	}
+
+	if len(badLines) > 0 {
+		return nil, &ParseError{Lines: badLines}
+	}
+
+	if opts.ExplicitDirs || slices.ContainsFunc(lines, func(input inputLine) bool {
+		return summaryLine.MatchString(strings.TrimSpace(input.text))
+	}) {
+		applyExplicitDirs(nodes, slashed)
+	}
+
+	return root, nil
+}
+
+// checkKinds returns an error for the first name that is used for both a file and a
+// directory in the same parent, like foo.d/ and foo.d, since Build would try to create
+// both at the same path. dir is the slash separated path of node
+func checkKinds(node *Node, dir string) error {
+	seen := make(map[string]*Node)
+	for _, child := range node.children {
+		fullPath := child.name
+		if dir != "" {
+			fullPath = dir + "/" + child.name
+		}
+		first, ok := seen[child.name]
+		if !ok {
+			seen[child.name] = child
+		} else if first.isDir != child.isDir {
+			file, directory := first, child
+			if first.isDir {
+				file, directory = child, first
+			}
+			if file.line > 0 && directory.line > 0 {
+				return fmt.Errorf("%s is declared as a file on line %d and as a directory on line %d", fullPath, file.line, directory.line)
+			}
+			return fmt.Errorf("%s is declared as both a file and a directory", fullPath)
+		}
+		if err := checkKinds(child, fullPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findDir returns the child directory of parent called name, or nil. it is used to
+// merge directories that are declared more than once
+func findDir(parent *Node, name string) *Node {
+	for _, child := range parent.children {
+		if child.isDir && child.name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// applyExplicitDirs handles fully annotated input such as the mode 1 tree. when every
+// entry with children is written with a trailing slash, names without one are files,
+// so extensionless files and empty directories survive a scan and re-parse. it is only
+// applied to input that looks like that output, see ParseOptions.ExplicitDirs
+func applyExplicitDirs(nodes []*Node, slashed map[*Node]bool) {
+	if len(slashed) == 0 {
+		return
+	}
+	for _, node := range nodes {
+		if len(node.children) > 0 && !slashed[node] {
+			return
+		}
+	}
+
+	for _, node := range nodes {
+		if !slashed[node] {
+			node.isDir = false
+		}
+	}
+}
+
+// splitPermAnnotation removes a trailing (0755) style mode from name
+func splitPermAnnotation(name string) (string, os.FileMode, bool) {
+	match := permAnnotation.FindStringSubmatch(name)
+	if match == nil {
+		return name, 0, false
+	}
+
+	perm, err := strconv.ParseUint(match[2], 8, 32)
+	if err != nil {
+		return name, 0, false
+	}
+	return match[1], os.FileMode(perm), true
+}
+
+// isDirName decides whether an input name is a directory. a trailing slash always
+// means directory, otherwise names without a dot are directories unless they are
+// known extensionless files or listed in FilesWithoutExt. names are compared case-insensitively
+func (opts ParseOptions) isDirName(name string) bool {
+	if strings.HasSuffix(name, "/") {
+		return true
+	}
+	if strings.Contains(name, ".") {
+		return false
+	}
+	lower := strings.ToLower(name)
+	return !filesWithoutExtensions[lower] && !slices.ContainsFunc(opts.FilesWithoutExt, func(file string) bool {
+		return strings.ToLower(file) == lower
+	})
+}
+
+// fencePrefix holds the characters that may come before a ``` fence inside a tree
+const fencePrefix = " \t│├└─|+-"
+
+// isFence reports whether line opens or closes a ``` fenced block
+func isFence(line string) bool {
+	return strings.HasPrefix(strings.TrimLeft(line, fencePrefix), "```")
+}
+
+// markdownFence matches the opening line of a Markdown code block and its info string
+var markdownFence = regexp.MustCompile("^\\s*(`{3,}|~{3,})\\s*(\\S*)")
+
+// extractMarkdownBlock returns the lines of the first code block tagged tree, or of the
+// first code block when none is tagged, along with the number of lines before it
+func extractMarkdownBlock(rawLines []string) ([]string, int, error) {
+	var first []string
+	firstOffset := -1
+	for i := 0; i < len(rawLines); i++ {
+		match := markdownFence.FindStringSubmatch(rawLines[i])
+		if match == nil {
+			continue
+		}
+
+		// the block ends at a fence of the same character that is at least as long
+		fence, tag := match[1], match[2]
+		start := i + 1
+		for i++; i < len(rawLines); i++ {
+			closing := strings.TrimSpace(rawLines[i])
+			if strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]) == "" {
+				break
+			}
+		}
+		if i == len(rawLines) {
+			return nil, 0, fmt.Errorf("line %d: code block is never closed", start)
+		}
+
+		if tag == "tree" {
+			return rawLines[start:i], start, nil
+		}
+		if firstOffset < 0 {
+			first, firstOffset = rawLines[start:i], start
+		}
+	}
+
+	if firstOffset < 0 {
+		return nil, 0, fmt.Errorf("no code block found in markdown input")
+	}
+	return first, firstOffset, nil
+}
+
+// extractTreeBlock returns the first run of drawn tree lines in rawLines, along with the
+// root line right above it, and the number of lines before it. everything around it,
+// like prose and the fences of a code block, is left out. a block has to have at least
+// one entry with a connector, so Markdown tables are not taken for a tree
+func extractTreeBlock(rawLines []string) ([]string, int, error) {
+	for start := 0; start < len(rawLines); start++ {
+		if !isTreeEntry(rawLines[start]) {
+			continue
+		}
+		end := start + 1
+		for end < len(rawLines) && (isTreeEntry(rawLines[end]) || isSpacerLine(rawLines[end])) {
+			end++
+		}
+		if start > 0 && isRootLine(rawLines[start-1]) {
+			start--
+		}
+		return rawLines[start:end], start, nil
+	}
+	return nil, 0, fmt.Errorf("no tree found in the input")
+}
+
+// isTreeEntry reports whether line is an entry drawn with a connector, like "│   ├── a"
+// or "│── a". a connector is ├ or └, or a bar, ` or + followed by a dash
+func isTreeEntry(line string) bool {
+	chars := []rune(line)
+	_, start := treePrefix(chars)
+	if start == len(chars) || strings.Trim(string(chars[:start]), treeChars) != "" {
+		return false
+	}
+	for i := 0; i < start; i++ {
+		if chars[i] == '├' || chars[i] == '└' {
+			return true
+		}
+		if strings.ContainsRune("│|`+", chars[i]) && i+1 < start && strings.ContainsRune("─-", chars[i+1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSpacerLine reports whether line only holds vertical bars, tree prints those
+// between groups of entries
+func isSpacerLine(line string) bool {
+	return strings.TrimSpace(line) != "" && strings.Trim(line, " \t│|") == ""
+}
+
+// isRootLine reports whether line can be the root above a drawn tree: a single name
+// like "myapp/" or ".", not prose like "the layout:" or a code block fence
+func isRootLine(line string) bool {
+	name := strings.TrimSpace(stripComment(line))
+	return name != "" && !strings.ContainsAny(name, " \t") && !strings.HasSuffix(name, ":") &&
+		!isFence(name) && !strings.HasPrefix(name, "~~~")
+}
+
+// cleanPaste undoes what copying a tree out of a terminal or a document adds: a shell
+// prompt line in front of it is blanked, and indentation shared by every line is
+// removed so the root starts at the first column. line numbers stay the same
+func cleanPaste(rawLines []string) []string {
+	lines := slices.Clone(rawLines)
+	for i, line := range lines {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			if promptLine.MatchString(trimmed) {
+				lines[i] = ""
+			}
+			break
+		}
+	}
+
+	prefix, found := "", false
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		indent := line[:len(line)-len(trimmed)]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix == "" {
+		return lines
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, prefix)
+	}
+	return lines
+}
+
+// splitFences separates structure lines from fenced blocks. the content of each
+// fenced block is attached to the structure line right before it. offset is the
+// number of input lines that come before rawLines, used for line numbers
+func splitFences(rawLines []string, offset int) ([]inputLine, error) {
+	var lines []inputLine
+	for i := 0; i < len(rawLines); i++ {
+		if !isFence(rawLines[i]) {
+			lines = append(lines, inputLine{text: rawLines[i], number: offset + i + 1})
+			continue
+		}
+
+		// find the entry the block belongs to, skipping blank and comment lines
+		owner := len(lines) - 1
+		for owner >= 0 {
+			trimmed := strings.TrimSpace(lines[owner].text)
+			if trimmed != "" && !isComment(trimmed) {
+				break
+			}
+			owner--
+		}
+		if owner < 0 {
+			return nil, fmt.Errorf("line %d: fenced block does not follow a file", offset+i+1)
+		}
+
+		openLine := i
+		indent := len([]rune(rawLines[i])) - len([]rune(strings.TrimLeft(rawLines[i], fencePrefix)))
+		var body []string
+		for i++; i < len(rawLines) && !isFence(rawLines[i]); i++ {
+			body = append(body, stripIndent(rawLines[i], indent))
+		}
+		if i == len(rawLines) {
+			return nil, fmt.Errorf("line %d: fenced block is never closed", offset+openLine+1)
+		}
+
+		content := ""
+		if len(body) > 0 {
+			content = strings.Join(body, "\n") + "\n"
+		}
+		lines[owner].content = &content
+	}
+	return lines, nil
+}
+
+// stripIndent removes up to width leading whitespace or tree runes from line
+func stripIndent(line string, width int) string {
+	chars := []rune(line)
+	i := 0
+	for i < width && i < len(chars) && strings.ContainsRune(fencePrefix, chars[i]) {
+		i++
+	}
+	return string(chars[i:])
+}
+
+// hasTreeCharacters reports whether any line is drawn with box-drawing or ASCII tree characters
+func hasTreeCharacters(lines []inputLine) bool {
+	for _, line := range lines {
+		chars := []rune(line.text)
+	prefix:
+		for i := range chars {
+			switch {
+			case isTreeGlyph(chars, i):
+				return true
+			case !strings.ContainsRune(" \t-─", chars[i]):
+				break prefix
+			}
+		}
+	}
+	return false
+}
+
+// isTreeGlyph reports whether chars[i] is a tree character. | always is, + and ` only
+// when they start a connector like +-- or `--, so names such as +page.svelte are kept
+func isTreeGlyph(chars []rune, i int) bool {
+	switch chars[i] {
+	case '│', '├', '└', '|':
+		return true
+	case '+', '`':
+		return i+1 < len(chars) && chars[i+1] == '-'
+	}
+	return false
+}
+
+// detectIndentUnit returns the number of leading spaces on the first space-indented line,
+// which is used as the width of one level in whitespace mode
+func detectIndentUnit(lines []inputLine) int {
+	for _, input := range lines {
+		line := input.text
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || isComment(trimmed) {
+			continue
+		}
+
+		spaces := len(line) - len(strings.TrimLeft(line, " "))
+		if spaces > 0 {
+			return spaces
+		}
+	}
+	return 1
+}
+
+// parseIndentedLine computes depth from leading whitespace. every tab is one level,
+// spaces are divided by the indent unit
+func parseIndentedLine(line string, indentUnit int) (int, string) {
+	var tabs, spaces int
+	i := 0
+	for ; i < len(line); i++ {
+		if line[i] == '\t' {
+			tabs++
+		} else if line[i] == ' ' {
+			spaces++
+		} else {
+			break
+		}
+	}
+
+	name := cleanName(strings.TrimLeft(line[i:], "- "))
+	if name == "" {
+		return 0, ""
+	}
+	return tabs + spaces/indentUnit, name
+}
+
+// cleanName removes trailing comments, symlink targets and leftover tree characters from a name
+func cleanName(name string) string {
+	name = stripComment(name)
+	name = strings.Split(name, " -> ")[0]
+	return strings.Trim(name, " ─│├└")
+}
+
+// rawName returns the name on a line as written, only the indentation and connector
+// with the one space after it are cut off, along with comments and symlink targets.
+// in indented lists a "- " bullet is part of the indentation
+func rawName(line string, indented bool) string {
+	var text string
+	if column, _ := treePrefix([]rune(line)); indented || column < 0 {
+		text = strings.TrimLeft(line, " \t")
+		if indented {
+			text = strings.TrimPrefix(text, "- ")
+		}
+	} else {
+		chars := []rune(line)
+		i := column + 1
+		for i < len(chars) && (chars[i] == '─' || chars[i] == '-') {
+			i++
+		}
+		if i < len(chars) && chars[i] == ' ' {
+			i++
+		}
+		text = string(chars[i:])
+	}
+
+	// the whitespace that separates a comment belongs to it
+	if name, comment := splitComment(text); comment != "" {
+		text = strings.TrimRight(name, " \t")
+	}
+	return strings.Split(text, " -> ")[0]
+}
+
+// isComment reports whether text starts with a # or // comment
+func isComment(text string) bool {
+	return strings.HasPrefix(text, "#") || strings.HasPrefix(text, "//")
+}
+
+// stripComment cuts name at a # or // that starts a comment. the delimiter has to be
+// at the start or follow whitespace, so names like C#.md or a://b are kept whole
+func stripComment(name string) string {
+	name, _ = splitComment(name)
+	return name
+}
+
+// splitComment splits text at the first comment marker that starts it or follows
+// whitespace, returning the text before it and the trimmed comment with its marker
+func splitComment(text string) (string, string) {
+	for i := 0; i < len(text); i++ {
+		if (i == 0 || text[i-1] == ' ' || text[i-1] == '\t') && isComment(text[i:]) {
+			return text[:i], strings.TrimSpace(text[i:])
+		}
+	}
+	return text, ""
+}
+
+// treeChars are the characters that can come before a name in a drawn tree
+const treeChars = " \t│├└─-|+`"
+
+// skipLine reports whether a line carries no entry: blank lines, the summary line
+// printed after a tree, spacer lines made of vertical bars and comment lines
+func skipLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || summaryLine.MatchString(trimmed) || strings.Trim(line, " \t│|") == "" ||
+		isComment(trimmed) || isComment(strings.TrimLeft(line, treeChars))
+}
+
+// parseLine computes depth from the column of the connector in front of the name, so
+// closed branches drawn with spaces and any gap width count the same. width is the
+// number of columns per level and base the column of the first level, see
+// detectTreeWidth. lines without a connector are depth 0
+func parseLine(line string, width, base int) (int, string) {
+	chars := []rune(line)
+	column, start := treePrefix(chars)
+	if start == len(chars) {
+		return 0, ""
+	}
+
+	name := cleanName(string(chars[start:]))
+	if column < 0 {
+		return 0, name
+	}
+	return max(column-base, 0)/width + 1, name
+}
+
+// treePrefix returns the column of the last tree character before the name, -1 when
+// there is none, and the index the name starts at
+func treePrefix(chars []rune) (int, int) {
+	column := -1
+	for i := range chars {
+		switch {
+		case isTreeGlyph(chars, i):
+			column = i
+		case !strings.ContainsRune(" \t-─", chars[i]):
+			return column, i
+		}
+	}
+	return column, len(chars)
+}
+
+// detectTreeWidth returns the number of columns one level takes in a drawn tree and the
+// column of the connectors on the first level. the first level is the leftmost
+// connector, 0 unless the tree is a subtree copied out of a larger one, and the width
+// is the smallest distance of another connector from it. it is 4 like tree and Print
+// draw it when every entry is on the first level. a connector like "├── " is as wide
+// as a level, so a level is never wider than the shortest one of at least 4 columns,
+// an entry indented too far then still is a depth jump instead of a wider level
+func detectTreeWidth(lines []inputLine) (int, int) {
+	var columns []int
+	connector := 0
+	for _, input := range lines {
+		chars := []rune(input.text)
+		column, start := treePrefix(chars)
+		if start == len(chars) || isComment(string(chars[start:])) || column < 0 {
+			continue
+		}
+		columns = append(columns, column)
+		if connector == 0 || start-column < connector {
+			connector = start - column
+		}
+	}
+	if len(columns) == 0 {
+		return 4, 0
+	}
+
+	base := slices.Min(columns)
+	width := 0
+	for _, column := range columns {
+		if column > base && (width == 0 || column-base < width) {
+			width = column - base
+		}
+	}
+	if width == 0 {
+		width = 4
+	}
+	if connector >= 4 && connector < width {
+		width = connector
+	}
+	return width, base
+}
@@ -0,0 +1,343 @@
+package scaffold
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// shape lists the path of every entry below root, directories with a trailing slash,
+// so two trees can be compared by structure alone
+func shape(root *Node) []string {
+	var out []string
+	var walk func(dir string, node *Node)
+	walk = func(dir string, node *Node) {
+		for _, child := range node.children {
+			entry := dir + child.name
+			if child.isDir {
+				entry += "/"
+				out = append(out, entry)
+				walk(entry, child)
+				continue
+			}
+			out = append(out, entry)
+		}
+	}
+	walk("", root)
+	slices.Sort(out)
+	return out
+}
+
+// writeTree creates the entries in dir, names ending in / are directories
+func writeTree(t testing.TB, dir string, entries ...string) {
+	t.Helper()
+	for _, entry := range entries {
+		fullPath := filepath.Join(dir, filepath.FromSlash(entry))
+		var err error
+		if strings.HasSuffix(entry, "/") {
+			err = os.MkdirAll(fullPath, 0755)
+		} else if err = os.MkdirAll(filepath.Dir(fullPath), 0755); err == nil {
+			err = os.WriteFile(fullPath, nil, 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHandWrittenNamesKeepHeuristic(t *testing.T) {
+	root := mustParse(t, "project/\n  cmd\n  docs\n  main.go\n")
+	want := []string{"project/", "project/cmd/", "project/docs/", "project/main.go"}
+	if got := shape(root); !slices.Equal(got, want) {
+		t.Errorf("shape = %q, want %q", got, want)
+	}
+}
+
+func TestScanPrintParseRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "proj")
+	writeTree(t, dir, "LICENSE", "bin/run", "src/main.go", "src/internal/", "docs/", "v1.0/", "assets/img.d/")
+
+	scanned, err := Scan(dir, ScanOptions{})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	var sb strings.Builder
+	if err := Render(&sb, scanned, "tree", PrintOptions{}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	parsed := mustParse(t, sb.String())
+
+	top := parsed.Children()
+	if len(top) != 1 || top[0].Name() != "proj" {
+		t.Fatalf("parsed top level = %q, want the proj directory", shape(parsed))
+	}
+	if got, want := shape(top[0]), shape(scanned); !slices.Equal(got, want) {
+		t.Errorf("round trip changed the tree\n got %q\nwant %q\nfrom:\n%s", got, want, sb.String())
+	}
+}
+
+// dotRootInput is tree run without an argument, pasted with its prompt
+const dotRootInput = `me@laptop:~/code/app$ tree
+.
+├── cmd
+│   └── main.go
+├── go.mod
+└── README.md
+
+1 directory, 3 files
+`
+
+func TestParseDotRoot(t *testing.T) {
+	root := mustParse(t, dotRootInput)
+	want := []string{"README.md", "cmd/", "cmd/main.go", "go.mod"}
+	if got := shape(root); !slices.Equal(got, want) {
+		t.Fatalf("shape = %q, want %q", got, want)
+	}
+	if problems := Validate(root); len(problems) > 0 {
+		t.Errorf("Validate: %v", problems)
+	}
+	if _, err := Build("/out", root, BuildOptions{FS: newMemFS(), Log: io.Discard}); err != nil {
+		t.Errorf("Build: %v", err)
+	}
+	if err := WriteScript(io.Discard, root, "sh", BuildOptions{}); err != nil {
+		t.Errorf("WriteScript: %v", err)
+	}
+	if !StripRootDir(root) || len(root.Children()) != 3 {
+		t.Errorf("StripRootDir changed a tree whose . root was already dropped: %q", shape(root))
+	}
+}
+
+func TestSplitPermAnnotation(t *testing.T) {
+	tests := []struct {
+		in      string
+		name    string
+		perm    os.FileMode
+		hasPerm bool
+	}{
+		{"run.sh (0755)", "run.sh", 0755, true},
+		{"secret.key (600)", "secret.key", 0600, true},
+		{"run.sh (4755)", "run.sh (4755)", 0, false},
+		{"tmp/ (1777)", "tmp/ (1777)", 0, false},
+		{"notes (draft).md", "notes (draft).md", 0, false},
+	}
+	for _, tt := range tests {
+		name, perm, hasPerm := splitPermAnnotation(tt.in)
+		if name != tt.name || perm != tt.perm || hasPerm != tt.hasPerm {
+			t.Errorf("splitPermAnnotation(%q) = %q, %o, %v, want %q, %o, %v", tt.in, name, perm, hasPerm, tt.name, tt.perm, tt.hasPerm)
+		}
+	}
+}
+
+func TestParseCRLF(t *testing.T) {
+	input := strings.ReplaceAll("app/\n├── cmd/\n│   └── main.go  # entry point\n├── Makefile\n└── README.md\n", "\n", "\r\n")
+	mem := newMemFS()
+	created, err := Build("/out", mustParse(t, input), BuildOptions{FS: mem, Log: io.Discard})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	want := []string{"/out/app", "/out/app/cmd", "/out/app/cmd/main.go", "/out/app/Makefile", "/out/app/README.md"}
+	if !slices.Equal(created, want) {
+		t.Errorf("created = %q, want %q", created, want)
+	}
+	for _, path := range mem.paths("/out") {
+		if strings.ContainsRune(path, '\r') {
+			t.Errorf("created name %q contains a carriage return", path)
+		}
+	}
+}
+
+func TestSplitComment(t *testing.T) {
+	tests := []struct {
+		in, name, comment string
+	}{
+		{"a.go # note", "a.go ", "# note"},
+		{"a.go // note", "a.go ", "// note"},
+		{"a.go\t# note", "a.go\t", "# note"},
+		{"C#.md", "C#.md", ""},
+		{"issue#42.txt", "issue#42.txt", ""},
+		{"C#.md # the C# notes", "C#.md ", "# the C# notes"},
+		{"a://b", "a://b", ""},
+		{"# only a comment", "", "# only a comment"},
+	}
+	for _, tt := range tests {
+		name, comment := splitComment(tt.in)
+		if name != tt.name || comment != tt.comment {
+			t.Errorf("splitComment(%q) = %q, %q, want %q, %q", tt.in, name, comment, tt.name, tt.comment)
+		}
+	}
+}
+
+func TestParseKeepsHashInNames(t *testing.T) {
+	root := mustParse(t, "docs/\n├── C#.md\n├── issue#42.txt\n└── a.go # note\n")
+	want := []string{"docs/", "docs/C#.md", "docs/a.go", "docs/issue#42.txt"}
+	if got := shape(root); !slices.Equal(got, want) {
+		t.Errorf("shape = %q, want %q", got, want)
+	}
+}
+
+// mustParseFile parses a file below testdata
+func mustParseFile(t *testing.T, name string) *Node {
+	t.Helper()
+	root, err := ParseFile(filepath.Join("testdata", name), ParseOptions{Log: io.Discard})
+	if err != nil {
+		t.Fatalf("ParseFile %s: %v", name, err)
+	}
+	return root
+}
+
+func TestParseASCIIMatchesUnicode(t *testing.T) {
+	unicode := mustParseFile(t, "tree_unicode.txt")
+	want := shape(unicode)
+	var wantPrint strings.Builder
+	Print(&wantPrint, unicode, PrintOptions{})
+
+	for _, name := range []string{"tree_ascii.txt", "tree_ascii_plus.txt"} {
+		ascii := mustParseFile(t, name)
+		if got := shape(ascii); !slices.Equal(got, want) {
+			t.Errorf("%s parses to %q, want %q", name, got, want)
+		}
+		var got strings.Builder
+		Print(&got, ascii, PrintOptions{})
+		if got.String() != wantPrint.String() {
+			t.Errorf("%s prints as\n%s\nwant\n%s", name, got.String(), wantPrint.String())
+		}
+	}
+}
+
+func TestParseMergesRepeatedDirectory(t *testing.T) {
+	root := mustParse(t, "app/\n├── src/  # sources\n│   └── a.go\n├── docs/\n└── src/ (0750)\n    └── b.go\n")
+	app := root.Children()[0]
+
+	var srcs []*Node
+	for _, child := range app.Children() {
+		if child.Name() == "src" {
+			srcs = append(srcs, child)
+		}
+	}
+	if len(srcs) != 1 {
+		t.Fatalf("app holds %d src directories, want one merged node", len(srcs))
+	}
+	src := srcs[0]
+	if len(src.Children()) != 2 || src.Children()[0].Name() != "a.go" || src.Children()[1].Name() != "b.go" {
+		t.Errorf("merged src holds %q, want a.go and b.go", shape(src))
+	}
+	if !src.hasPerm || src.perm != 0750 {
+		t.Errorf("merged src has mode %o, want the 0750 of the second declaration", src.perm)
+	}
+	if src.lineComment != "# sources" || src.Line() != 2 {
+		t.Errorf("merged src has comment %q from line %d, want the first declaration's", src.lineComment, src.Line())
+	}
+}
+
+func TestEmptyDirsSurviveCreate(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "proj")
+	writeTree(t, dir, "docs/", "v1.0/", "assets/img.d/", "src/internal/", "src/main.go")
+
+	scanned, err := Scan(dir, ScanOptions{})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	var sb strings.Builder
+	if err := Render(&sb, scanned, "tree", PrintOptions{}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	out := filepath.Join(base, "out")
+	if _, err := Build(out, mustParse(t, sb.String()), BuildOptions{Log: io.Discard}); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	created, err := Scan(filepath.Join(out, "proj"), ScanOptions{})
+	if err != nil {
+		t.Fatalf("Scan of the created tree: %v", err)
+	}
+	if got, want := shape(created), shape(scanned); !slices.Equal(got, want) {
+		t.Errorf("created tree differs from the scanned one\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestParseTreeWidths(t *testing.T) {
+	want := []string{"app/", "app/cmd/", "app/cmd/server/", "app/cmd/server/main.go", "app/go.mod"}
+	for _, indent := range []int{2, 3, 4, 6} {
+		dir := filepath.Join(t.TempDir(), "app")
+		writeTree(t, dir, "cmd/server/main.go", "go.mod")
+		scanned, err := Scan(dir, ScanOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, spaces := range []bool{false, true} {
+			var sb strings.Builder
+			Print(&sb, scanned, PrintOptions{Indent: indent, IndentSpaces: spaces})
+			if got := shape(mustParse(t, sb.String())); !slices.Equal(got, want) {
+				t.Errorf("indent %d, spaces %v: parsed to %q, want %q from\n%s", indent, spaces, got, want, sb.String())
+			}
+		}
+	}
+}
+
+func TestParseHandDrawnWidths(t *testing.T) {
+	tests := map[string]string{
+		"2 columns": "app/\n├─ cmd/\n│ └─ main.go\n└─ go.mod\n",
+		"4 columns": "app/\n├── cmd/\n│   └── main.go\n└── go.mod\n",
+	}
+	want := []string{"app/", "app/cmd/", "app/cmd/main.go", "app/go.mod"}
+	for name, input := range tests {
+		if got := shape(mustParse(t, input)); !slices.Equal(got, want) {
+			t.Errorf("%s: parsed to %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParseDepthJump(t *testing.T) {
+	for _, input := range []string{
+		"app/\n├── a/\n│       └── deep.go\n",
+		"app/\n├── a/\n│   │   └── deep.go\n",
+	} {
+		_, err := Parse(strings.NewReader(input), ParseOptions{Log: io.Discard})
+		if err == nil || !strings.Contains(err.Error(), "depth jumps") {
+			t.Errorf("Parse(%q) = %v, want a depth jump error", input, err)
+		}
+	}
+}
+
+func TestParsePastedFixtures(t *testing.T) {
+	tests := []struct {
+		file string
+		opts ParseOptions
+		want []string
+	}{
+		{
+			file: "pasted.txt",
+			want: []string{"README.md", "cmd/", "cmd/pasted/", "cmd/pasted/main.go", "go.mod", "internal/", "internal/server/", "internal/server/handler.go", "internal/server/server.go"},
+		},
+		{
+			file: "subtree.txt",
+			want: []string{"handlers/", "handlers/health.go", "handlers/users.go", "middleware/", "middleware/auth.go", "router.go"},
+		},
+		{
+			file: "chat.txt",
+			opts: ParseOptions{Lenient: true},
+			want: []string{"webapp/", "webapp/README.md", "webapp/cmd/", "webapp/cmd/server/", "webapp/cmd/server/main.go", "webapp/go.mod",
+				"webapp/internal/", "webapp/internal/handlers/", "webapp/internal/handlers/health.go", "webapp/internal/store/", "webapp/internal/store/store.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			tt.opts.Log = io.Discard
+			root, err := ParseFile(filepath.Join("testdata", tt.file), tt.opts)
+			if err != nil {
+				t.Fatalf("ParseFile: %v", err)
+			}
+			if got := shape(root); !slices.Equal(got, tt.want) {
+				t.Errorf("shape = %q, want %q", got, tt.want)
+			}
+			if problems := Validate(root); len(problems) > 0 {
+				t.Errorf("Validate: %v", problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package scaffold
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// PrintOptions controls how Print renders a node tree
+type PrintOptions struct {
+	ShowSize  bool // append sizes recorded by Scan with WithSize
+	ShowLines bool // append line counts recorded by Scan with WithLines
+	ASCII     bool // draw with |, |-- and `-- instead of box-drawing characters
+	Color     bool // color directory and symlink names with ANSI escapes like tree
+
+	FlatDirs   bool   // list directories with a trailing slash in the flat format, not only files
+	PathPrefix string // slash separated path put in front of every path in the flat format
+
+	FileColors bool // with Color, also color files by their extension
+	Icons      bool // put a Nerd Font icon for the file type in front of every name
+
+	RootName string // shown on the top line of the tree drawing instead of the root's name
+
+	Indent       int  // columns per level in the tree drawing, 4 when 0, at least 2
+	IndentSpaces bool // draw the levels of open ancestors with spaces instead of a vertical bar
+
+	Comments bool // print the comments kept from a parsed input, see CopyComments
+}
+
+// ANSI escapes used when PrintOptions.Color is set
+const (
+	colorDir   = "\x1b[1;34m"
+	colorLink  = "\x1b[1;36m"
+	colorReset = "\x1b[0m"
+)
+
+// treeGlyphs are the segments a tree line is drawn from
+type treeGlyphs struct {
+	open   string // an ancestor that has more children below
+	closed string // an ancestor whose last child was already drawn
+	branch string // connector for a child with siblings after it
+	last   string // connector for the last child
+}
+
+// glyphs returns the segments for the character set and indent selected by opts. the
+// connector of a child is always followed by a space, so with an indent of 2 it is one
+// column longer than a level, like "│ └─ name"
+func (opts PrintOptions) glyphs() treeGlyphs {
+	bar, tee, corner, dash := "│", "├", "└", "─"
+	if opts.ASCII {
+		bar, tee, corner, dash = "|", "|", "`", "-"
+	}
+	if opts.IndentSpaces {
+		bar = " "
+	}
+	width := opts.Indent
+	if width == 0 {
+		width = 4
+	}
+	width = max(width, 2)
+
+	dashes := strings.Repeat(dash, max(width-2, 1))
+	return treeGlyphs{
+		open:   bar + strings.Repeat(" ", width-1),
+		closed: strings.Repeat(" ", width),
+		branch: tee + dashes + " ",
+		last:   corner + dashes + " ",
+	}
+}
+
+// Print writes the tree drawing of root to w
+func Print(w io.Writer, root *Node, opts PrintOptions) {
+	printTree(w, root, nil, opts)
+}
+
+// String returns the tree drawing of n and everything below it, as Print writes it
+// with the default options. it makes Node a fmt.Stringer, e.g. for debugging
+func (n *Node) String() string {
+	var sb strings.Builder
+	Print(&sb, n, PrintOptions{})
+	return sb.String()
+}
+
+// Render writes root to w in the given format. "tree" is the Print drawing followed
+// by a directory and file count, "json" is the MarshalJSON document and "yaml" is a
+// mapping of directories to their entries that Parse can read back with YAML set.
+// "dot" is a Graphviz graph with an edge from every directory to its entries and
+// "flat" lists the path of every file below root, one per line
+func Render(w io.Writer, root *Node, format string, opts PrintOptions) error {
+	switch format {
+	case "tree":
+		printTree(w, root, nil, opts)
+		dirs, files := countNodes(root)
+		fmt.Fprintf(w, "\n%d %s, %d %s\n", dirs, plural(dirs, "directory", "directories"), files, plural(files, "file", "files"))
+	case "json":
+		data, err := json.MarshalIndent(root, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding tree: %w", err)
+		}
+		fmt.Fprintln(w, string(data))
+	case "yaml":
+		return writeYAML(w, root)
+	case "dot":
+		return writeDOT(w, root)
+	case "flat":
+		writeFlat(w, root, opts)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return nil
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// writeFlat prints the slash separated path of every entry below root in walk order,
+// after opts.PathPrefix. directories are only listed with opts.FlatDirs
+func writeFlat(w io.Writer, root *Node, opts PrintOptions) {
+	for _, child := range root.children {
+		child.Walk(func(n *Node) error {
+			var parts []string
+			for p := n; p != root; p = p.parent {
+				parts = append(parts, p.name)
+			}
+			slices.Reverse(parts)
+			entry := path.Join(opts.PathPrefix, strings.Join(parts, "/"))
+			switch {
+			case !n.isDir:
+				fmt.Fprintln(w, entry)
+			case opts.FlatDirs:
+				fmt.Fprintln(w, entry+"/")
+			}
+			return nil
+		})
+	}
+}
+
+// countNodes counts the directories and files below node, node itself is not counted
+func countNodes(node *Node) (int, int) {
+	var dirs, files int
+	node.Walk(func(n *Node) error {
+		if n == node {
+			return nil
+		}
+		if n.isDir {
+			dirs++
+		} else {
+			files++
+		}
+		return nil
+	})
+	return dirs, files
+}
+
+// printTree prints node and its children. isLast holds one entry per level below the
+// root telling whether the node on that level is the last child of its parent, so
+// branches that are already closed are drawn with spaces instead of a bar and the
+// last child gets the └── connector. the indentation comes from the recursion level
+// alone, the stored depth is not used so parsed and scanned trees print the same way
+func printTree(w io.Writer, node *Node, isLast []bool, opts PrintOptions) {
+
+	glyphs := opts.glyphs()
+	if opts.Comments {
+		// comment lines sit above the entry, on the bar that leads to its connector
+		for _, comment := range node.headComment {
+			for i := range isLast {
+				if i < len(isLast)-1 && isLast[i] {
+					fmt.Fprint(w, glyphs.closed)
+				} else {
+					fmt.Fprint(w, glyphs.open)
+				}
+			}
+			fmt.Fprintln(w, comment)
+		}
+	}
+	for i := range isLast {
+		if i < len(isLast)-1 {
+			if isLast[i] {
+				fmt.Fprint(w, glyphs.closed)
+			} else {
+				fmt.Fprint(w, glyphs.open)
+			}
+		} else if isLast[i] {
+			fmt.Fprint(w, glyphs.last)
+		} else {
+			fmt.Fprint(w, glyphs.branch)
+		}
+	}
+
+	label := node.name
+	if len(isLast) == 0 && opts.RootName != "" {
+		label = opts.RootName
+	}
+	switch {
+	case node.truncated:
+		label = opts.colored(colorDir, label+"/") + "..."
+	case node.isDir:
+		label = opts.colored(colorDir, label+"/")
+	case node.linkTarget != "":
+		label = opts.colored(colorLink, label) + " -> " + node.linkTarget
+	case opts.FileColors:
+		label = opts.colored(styleOf(node).color, label)
+	}
+	if opts.Icons {
+		label = styleOf(node).icon + " " + label
+	}
+	if node.denied {
+		label += " [permission denied]"
+	}
+	if opts.ShowSize {
+		label += " [" + humanSize(node.size) + "]"
+	}
+	if opts.ShowLines {
+		label += " [" + lineCount(node.lines) + "]"
+	}
+	if opts.Comments && node.lineComment != "" {
+		label += "  " + node.lineComment
+	}
+	fmt.Fprintln(w, label)
+
+	for i := range node.children {
+		last := i == len(node.children)-1
+		printTree(w, node.children[i], append(isLast[:len(isLast):len(isLast)], last), opts)
+	}
+}
+
+// colored wraps s in the given ANSI color when colors are enabled
+func (opts PrintOptions) colored(color, s string) string {
+	if !opts.Color || color == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// lineCount formats a line count, binary and unreadable files are shown as -
+func lineCount(lines int64) string {
+	if lines < 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d %s", lines, plural(int(lines), "line", "lines"))
+}
+
+// humanSize formats a byte count like tree -h does, e.g. 512, 4.0K, 1.2M
+func humanSize(size int64) string {
+	if size < 0 {
+		return "?"
+	}
+
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,46 @@
+package scaffold
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const deepTree = `proj/
+├── a/
+│   ├── b/
+│   │   ├── c/
+│   │   │   └── deep.txt
+│   │   └── c2.txt
+│   └── x.txt
+└── z.txt
+`
+
+func TestPrintDeepPrefixes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "proj")
+	writeTree(t, dir, "a/b/c/deep.txt", "a/b/c2.txt", "a/x.txt", "z.txt")
+
+	scanned, err := Scan(dir, ScanOptions{})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	var sb strings.Builder
+	Print(&sb, scanned, PrintOptions{})
+	if got := sb.String(); got != deepTree {
+		t.Errorf("scanned tree prints as\n%s\nwant\n%s", got, deepTree)
+	}
+
+	// a parsed tree has the same depths as a scanned one and prints the same way
+	parsed := mustParse(t, deepTree).Children()[0]
+	sb.Reset()
+	Print(&sb, parsed, PrintOptions{})
+	if got := sb.String(); got != deepTree {
+		t.Errorf("parsed tree prints as\n%s\nwant\n%s", got, deepTree)
+	}
+	scannedDeep, _ := scanned.Find("a/b/c/deep.txt")
+	parsedDeep, _ := parsed.Find("a/b/c/deep.txt")
+	if scannedDeep.Depth()-scanned.Depth() != 4 || parsedDeep.Depth()-parsed.Depth() != 4 {
+		t.Errorf("deep.txt is at depth %d in the scan and %d in the parsed tree, want 4 below the top directory in both",
+			scannedDeep.Depth()-scanned.Depth(), parsedDeep.Depth()-parsed.Depth())
+	}
+}
@@ -0,0 +1,497 @@
+package scaffold
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultIgnore lists the names Scan always skips
+var DefaultIgnore = []string{".gitignore", ".git", IgnoreFileName}
+
+// IgnoreFileName is read from the root of a scanned directory for extra ignore patterns
+const IgnoreFileName = ".ftpignore"
+
+// SortOrders lists the values ScanOptions.Sort accepts. size puts the largest entry
+// first and mtime the newest, like ls -S and ls -t, ties are broken by name
+var SortOrders = []string{"name", "dirs-first", "files-first", "size", "mtime"}
+
+// ScanOptions controls how Scan walks an existing directory
+type ScanOptions struct {
+	MaxDepth         int       // how deep to descend below the root, 0 means unlimited
+	Ignore           []string  // glob patterns matched against base names after DefaultIgnore, !pattern re-includes
+	RespectGitignore bool      // skip paths matched by the root .gitignore
+	FollowSymlinks   bool      // descend into symlinked directories
+	WithSize         bool      // record file sizes and directory totals
+	IncludeHidden    bool      // keep entries whose name starts with a dot
+	Concurrency      int       // directories read in parallel, 0 means GOMAXPROCS
+	WithModTime      bool      // record modification times
+	WithLines        bool      // count the lines of text files and sum them per directory
+	WithMode         bool      // record permission bits so Build can restore them
+	WithHash         bool      // record the SHA-256 of every file so Diff can detect changed contents
+	Since            time.Time // only keep files modified after this time and the directories holding them, zero keeps everything
+	Sort             string    // order of the children of every directory, one of SortOrders, dirs-first when empty
+	Reverse          bool      // reverse the Sort order
+	MaxNesting       int       // fail on directories nested deeper than this, DefaultMaxNesting when 0 and unlimited when negative
+	Normalize        string    // Unicode normalization form for names, "nfc" or "nfd", see NormalizeNames
+	Strict           bool      // fail on unreadable directories and MaxEntries instead of skipping with a warning
+	MaxEntries       int       // stop after visiting this many entries in total, 0 means unlimited. the scan is sequential then
+	Log              io.Writer // warnings about skipped directories, os.Stderr when nil
+
+	root      string        // path the scan started from
+	ignore    []string      // every ignore pattern in effect
+	gitignore *gitignore    // rules from the root .gitignore, nil when not respected
+	ancestors []os.FileInfo // directories on the current path, used to break symlink cycles
+	workers   chan struct{} // free slots for extra goroutines, shared by the whole scan
+	entries   *atomic.Int64 // entries visited so far, shared by the whole scan for MaxEntries
+}
+
+// ErrTooManyEntries is wrapped by the error Scan returns when ScanOptions.MaxEntries is
+// exceeded with ScanOptions.Strict set
+var ErrTooManyEntries = errors.New("too many entries")
+
+// dirResult holds the outcome of scanning one subdirectory
+type dirResult struct {
+	node *Node
+	err  error
+}
+
+// Scan builds a tree from the directory at path. the root node is named after the
+// base name of path. ignore patterns from a .ftpignore file in path are applied too
+func Scan(path string, opts ScanOptions) (*Node, error) {
+	switch opts.Sort {
+	case "":
+		opts.Sort = "dirs-first"
+	case "size":
+		opts.WithSize = true
+	case "mtime":
+		opts.WithModTime = true
+	default:
+		if !slices.Contains(SortOrders, opts.Sort) {
+			return nil, fmt.Errorf("unknown sort order %q, use one of %s", opts.Sort, strings.Join(SortOrders, ", "))
+		}
+	}
+
+	opts.root = path
+	opts.ignore = append(append([]string{}, DefaultIgnore...), opts.Ignore...)
+
+	patterns, err := loadIgnoreFile(filepath.Join(path, IgnoreFileName))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", IgnoreFileName, err)
+	}
+	opts.ignore = append(opts.ignore, patterns...)
+
+	if opts.RespectGitignore {
+		opts.gitignore, err = loadGitignore(filepath.Join(path, ".gitignore"))
+		if err != nil {
+			return nil, fmt.Errorf("error reading .gitignore: %w", err)
+		}
+	}
+
+	// the calling goroutine is one of the workers
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	// parallel workers would race for the MaxEntries budget, so which directories are
+	// cut short would depend on scheduling. in order, the same entries are always kept
+	if opts.MaxEntries > 0 {
+		concurrency = 1
+	}
+	opts.workers = make(chan struct{}, concurrency-1)
+	opts.entries = new(atomic.Int64)
+
+	root, err := createTree(path, 0, opts)
+	if err == nil && opts.MaxEntries > 0 && opts.entries.Load() > int64(opts.MaxEntries) {
+		fmt.Fprintf(opts.log(), "warning: stopped scanning after %d entries, directories marked with /... are incomplete\n", opts.MaxEntries)
+	}
+	if err != nil || opts.Normalize == "" {
+		return root, err
+	}
+	if err := NormalizeNames(root, opts.Normalize); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// this function will create a tree structure in the given path and subdirectories
+func createTree(path string, depth int, opts ScanOptions) (*Node, error) {
+
+	// start with the root directory and create the tree structure recursively
+	directoryName := filepath.Base(path)
+	if depth > 0 && (opts.isHidden(directoryName) || opts.isIgnored(directoryName) || opts.gitignored(path, true)) {
+		// skip the ignored directory
+		return nil, nil
+	}
+
+	if tooDeep(depth, opts.MaxNesting) {
+		return nil, fmt.Errorf("%s is %w, the limit is %d levels", path, ErrTooDeep, nestingLimit(opts.MaxNesting))
+	}
+
+	parent := &Node{
+		name:  directoryName,
+		isDir: true,
+		depth: depth,
+	}
+
+	// list the files and directories in the current directory
+	files, err := os.ReadDir(path)
+	if err != nil && depth > 0 && !opts.Strict && errors.Is(err, fs.ErrPermission) {
+		// one unreadable directory should not stop a scan of a large tree
+		fmt.Fprintf(opts.log(), "warning: skipping %s: permission denied\n", path)
+		parent.denied = true
+		return parent, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", path, err)
+	}
+
+	if opts.WithModTime || opts.WithMode {
+		if info, err := os.Stat(path); err == nil {
+			if opts.WithModTime {
+				parent.modTime = info.ModTime()
+			}
+			if opts.WithMode {
+				parent.perm, parent.hasPerm = info.Mode().Perm(), true
+			}
+		}
+	}
+
+	// stop descending at the depth limit but remember that there is more below
+	if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+		parent.truncated = len(files) > 0
+		return parent, nil
+	}
+
+	if opts.FollowSymlinks {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading directory %s: %w", path, err)
+		}
+		opts.ancestors = append(opts.ancestors[:len(opts.ancestors):len(opts.ancestors)], info)
+	}
+
+	// subdirectories are scanned on a free worker when there is one and inline otherwise,
+	// so a full pool never blocks. results are merged in entry order afterwards
+	var wg sync.WaitGroup
+	results := make([]*dirResult, len(files))
+
+	for i := range files {
+		if opts.MaxEntries > 0 && opts.entries.Add(1) > int64(opts.MaxEntries) {
+			if opts.Strict {
+				return nil, fmt.Errorf("%w: %s has more than %d entries", ErrTooManyEntries, opts.root, opts.MaxEntries)
+			}
+			parent.truncated = true
+			break
+		}
+
+		isDir := files[i].IsDir()
+		var linkTarget string
+		if files[i].Type()&os.ModeSymlink != 0 {
+			isDir, linkTarget = opts.resolveSymlink(filepath.Join(path, files[i].Name()))
+		}
+
+		if isDir {
+			// recursively create the tree for the subdirectory
+			subDirPath := filepath.Join(path, files[i].Name())
+			result := &dirResult{}
+			results[i] = result
+
+			select {
+			case opts.workers <- struct{}{}:
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					result.node, result.err = createTree(subDirPath, depth+1, opts)
+					<-opts.workers
+				}()
+			default:
+				result.node, result.err = createTree(subDirPath, depth+1, opts)
+			}
+		} else {
+			if opts.isHidden(files[i].Name()) || opts.isIgnored(files[i].Name()) || opts.gitignored(filepath.Join(path, files[i].Name()), false) {
+				// skip the ignored file
+				continue
+			}
+
+			var info os.FileInfo
+			if opts.WithModTime || opts.WithMode || !opts.Since.IsZero() {
+				info, _ = files[i].Info()
+			}
+			if !opts.Since.IsZero() && (info == nil || info.ModTime().Before(opts.Since)) {
+				continue
+			}
+
+			node := &Node{
+				name:       files[i].Name(),
+				isDir:      false,
+				parent:     parent,
+				depth:      parent.depth + 1,
+				linkTarget: linkTarget,
+				source:     filepath.Join(path, files[i].Name()),
+			}
+
+			if opts.WithModTime && linkTarget == "" && info != nil {
+				node.modTime = info.ModTime()
+			}
+			if opts.WithMode && linkTarget == "" && info != nil {
+				node.perm, node.hasPerm = info.Mode().Perm(), true
+			}
+
+			if opts.WithSize {
+				node.size = entrySize(files[i])
+				if node.size > 0 {
+					parent.size += node.size
+				}
+			}
+
+			if opts.WithHash && linkTarget == "" {
+				node.hash = fileHash(filepath.Join(path, files[i].Name()))
+			}
+
+			if opts.WithLines {
+				node.lines = -1
+				if linkTarget == "" {
+					node.lines = countLines(filepath.Join(path, files[i].Name()))
+				}
+				if node.lines > 0 {
+					parent.lines += node.lines
+				}
+			}
+
+			parent.children = append(parent.children, node)
+		}
+	}
+
+	wg.Wait()
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		if errors.Is(result.err, ErrTooDeep) || errors.Is(result.err, ErrTooManyEntries) {
+			// already names the full path, wrapping it once per level would repeat it hundreds of times
+			return nil, result.err
+		}
+		if result.err != nil {
+			return nil, fmt.Errorf("error creating tree for directory %s: %w", filepath.Join(path, files[i].Name()), result.err)
+		}
+
+		// with Since, directories without a recent file are left out too
+		if result.node != nil && !opts.Since.IsZero() && len(result.node.children) == 0 && !result.node.truncated {
+			continue
+		}
+
+		// add the subdirectory node to the parent node
+		if result.node != nil {
+			result.node.parent = parent
+			parent.children = append(parent.children, result.node)
+			parent.size += result.node.size
+			parent.lines += result.node.lines
+		}
+	}
+
+	opts.sortChildren(parent.children)
+
+	return parent, nil
+}
+
+// loadIgnoreFile returns the patterns in filename. blank lines and lines starting
+// with # are skipped. a missing file is not an error
+func loadIgnoreFile(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// entrySize returns the size of a directory entry, or -1 when it can't be read
+func entrySize(entry os.DirEntry) int64 {
+	info, err := entry.Info()
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
+
+// fileHash returns the hex SHA-256 of a file, streamed so large files are not read
+// into memory. it is empty when the file can not be read
+func fileHash(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// countLines counts the newlines in a file. files with a NUL byte in their first
+// chunk are binary and, like unreadable files, return -1
+func countLines(path string) int64 {
+	file, err := os.Open(path)
+	if err != nil {
+		return -1
+	}
+	defer file.Close()
+
+	var lines int64
+	buf := make([]byte, 32*1024)
+	for first := true; ; first = false {
+		n, err := file.Read(buf)
+		if first && bytes.IndexByte(buf[:n], 0) >= 0 {
+			return -1
+		}
+		lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			return -1
+		}
+	}
+}
+
+// resolveSymlink decides how a symlink found while scanning is shown. unless links are
+// followed it is a leaf with its target. a followed link to a directory is descended
+// into, except when it points back to a directory that is already being scanned
+func (opts ScanOptions) resolveSymlink(path string) (bool, string) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		target = "?"
+	}
+	if !opts.FollowSymlinks {
+		return false, target
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		// broken link
+		return false, target
+	}
+	if !info.IsDir() {
+		return false, ""
+	}
+	for _, ancestor := range opts.ancestors {
+		if os.SameFile(ancestor, info) {
+			return false, target + " [recursive, not followed]"
+		}
+	}
+	return true, ""
+}
+
+// gitignored reports whether path is excluded by the root .gitignore rules
+func (opts ScanOptions) gitignored(path string, isDir bool) bool {
+	if opts.gitignore == nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(opts.root, path)
+	if err != nil {
+		return false
+	}
+	return opts.gitignore.match(filepath.ToSlash(rel), isDir)
+}
+
+// isHidden reports whether name is a dotfile that should be left out, like tree does by default
+func (opts ScanOptions) isHidden(name string) bool {
+	return !opts.IncludeHidden && strings.HasPrefix(name, ".")
+}
+
+// log returns the writer warnings go to
+func (opts ScanOptions) log() io.Writer {
+	if opts.Log == nil {
+		return os.Stderr
+	}
+	return opts.Log
+}
+
+// isIgnored reports whether name matches the ignore entries. entries are glob patterns
+// matched against the base name only, a plain name matches exactly. a pattern starting
+// with ! re-includes names an earlier pattern ignored. patterns are evaluated in order
+// and the last one that matches decides, like in a .gitignore
+func (opts ScanOptions) isIgnored(name string) bool {
+	ignored := false
+	for _, pattern := range opts.ignore {
+		negated := strings.HasPrefix(pattern, "!")
+		if matched, err := filepath.Match(strings.TrimPrefix(pattern, "!"), name); err == nil && matched {
+			ignored = !negated
+		}
+	}
+	return ignored
+}
+
+// sortChildren orders the entries of a directory by opts.Sort. the default dirs-first
+// lists directories, then files, both alphabetically like tree(1) does
+func (opts ScanOptions) sortChildren(children []*Node) {
+	sort.SliceStable(children, func(i, j int) bool {
+		if opts.Reverse {
+			return opts.compare(children[j], children[i]) < 0
+		}
+		return opts.compare(children[i], children[j]) < 0
+	})
+}
+
+// compare returns a negative number when a comes before b in opts.Sort order
+func (opts ScanOptions) compare(a, b *Node) int {
+	switch opts.Sort {
+	case "dirs-first":
+		if a.isDir != b.isDir {
+			return boolOrder(a.isDir)
+		}
+	case "files-first":
+		if a.isDir != b.isDir {
+			return boolOrder(!a.isDir)
+		}
+	case "size":
+		if c := cmp.Compare(b.size, a.size); c != 0 {
+			return c
+		}
+	case "mtime":
+		if c := b.modTime.Compare(a.modTime); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a.name, b.name)
+}
+
+// boolOrder is -1 when first is true, so that entry sorts before the other one
+func boolOrder(first bool) int {
+	if first {
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,97 @@
+package scaffold
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeWideTree creates a tree of depth levels below dir with width directories and
+// files in every directory, for comparing sequential and concurrent scans
+func writeWideTree(t testing.TB, dir string, depth, width int) {
+	t.Helper()
+	var entries []string
+	var add func(prefix string, level int)
+	add = func(prefix string, level int) {
+		for i := range width {
+			entries = append(entries, fmt.Sprintf("%sfile%d.go", prefix, i))
+			if level < depth {
+				sub := fmt.Sprintf("%sdir%d/", prefix, i)
+				entries = append(entries, sub)
+				add(sub, level+1)
+			}
+		}
+	}
+	add("", 1)
+	writeTree(t, dir, entries...)
+}
+
+// parallelism is the concurrency compared with a sequential scan, at least 4 so the
+// worker pool is used even on a machine with a single CPU
+func parallelism() int {
+	return max(runtime.GOMAXPROCS(0), 4)
+}
+
+func TestScanConcurrencySameOutput(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "wide")
+	writeWideTree(t, dir, 3, 5)
+
+	render := func(concurrency int) string {
+		root, err := Scan(dir, ScanOptions{Concurrency: concurrency, WithSize: true})
+		if err != nil {
+			t.Fatalf("Scan with concurrency %d: %v", concurrency, err)
+		}
+		var sb strings.Builder
+		if err := Render(&sb, root, "json", PrintOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		return sb.String()
+	}
+
+	sequential := render(1)
+	for range 5 {
+		if concurrent := render(parallelism()); concurrent != sequential {
+			t.Fatalf("concurrent scan differs from the sequential one\nsequential:\n%s\nconcurrent:\n%s", sequential, concurrent)
+		}
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	dir := filepath.Join(b.TempDir(), "wide")
+	writeWideTree(b, dir, 4, 6)
+
+	for _, concurrency := range []int{1, parallelism()} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := Scan(dir, ScanOptions{Concurrency: concurrency}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestScanMaxEntriesDeterministic(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "wide")
+	writeWideTree(t, dir, 3, 6)
+
+	render := func(concurrency int) string {
+		root, err := Scan(dir, ScanOptions{Concurrency: concurrency, MaxEntries: 40, Log: io.Discard})
+		if err != nil {
+			t.Fatalf("Scan with concurrency %d: %v", concurrency, err)
+		}
+		var sb strings.Builder
+		Print(&sb, root, PrintOptions{})
+		return sb.String()
+	}
+
+	sequential := render(1)
+	for range 10 {
+		if concurrent := render(parallelism()); concurrent != sequential {
+			t.Fatalf("scan with MaxEntries depends on the concurrency\nsequential:\n%s\nconcurrent:\n%s", sequential, concurrent)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package scaffold
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// scriptEntry is a directory or file a script creates, with its path relative to
+// the directory the script is run in
+type scriptEntry struct {
+	path string
+	node *Node
+}
+
+// WriteScript writes a script to w that creates the children of root in the directory
+// it is run in, for machines this tool is not installed on. shell is "sh" for a POSIX
+// shell script or "ps1" for PowerShell. file contents come from the same places as in
+// Build and opts.Vars placeholders are replaced. like Build, opts.MaxDepth limits the
+// levels that are created, opts.Placeholder is put in every empty directory and
+// existing files are only overwritten with opts.Force.
+// recorded permissions are set by the sh script only, windows has no equivalent
+func WriteScript(w io.Writer, root *Node, shell string, opts BuildOptions) error {
+	if shell != "sh" && shell != "ps1" {
+		return fmt.Errorf("unknown script type %q, use sh or ps1", shell)
+	}
+	if len(opts.Vars) > 0 {
+		root.Walk(func(n *Node) error {
+			n.name = opts.replaceVars(n.name)
+			return nil
+		})
+	}
+	if opts.Placeholder != "" {
+		addPlaceholders(".", root, opts, false)
+	}
+	if err := validateNames(".", root); err != nil {
+		return err
+	}
+
+	// every directory is created first, so the files can be written in any order
+	var dirs, files []scriptEntry
+	opts.rootDepth = root.depth
+	var collect func(dir string, node *Node)
+	collect = func(dir string, node *Node) {
+		if opts.belowMaxDepth(node) {
+			return
+		}
+		for _, child := range node.children {
+			entry := scriptEntry{path: path.Join(dir, child.name), node: child}
+			if child.isDir {
+				dirs = append(dirs, entry)
+				collect(entry.path, child)
+			} else {
+				files = append(files, entry)
+			}
+		}
+	}
+	collect("", root)
+
+	contents := make([]string, len(files))
+	for i, file := range files {
+		content, err := fileContent(file.node, path.Dir(file.path), opts)
+		if err != nil {
+			return err
+		}
+		contents[i] = opts.replaceVars(content)
+	}
+
+	if shell == "ps1" {
+		writePowerShell(w, dirs, files, contents, opts.Force)
+	} else {
+		writeShell(w, dirs, files, contents, opts.Force)
+	}
+	return nil
+}
+
+// writeShell writes the POSIX shell version of WriteScript
+func writeShell(w io.Writer, dirs, files []scriptEntry, contents []string, force bool) {
+	fmt.Fprintln(w, "#!/bin/sh")
+	fmt.Fprintln(w, "# run in the directory the structure should be created in")
+	fmt.Fprintln(w, "set -e")
+	for _, dir := range dirs {
+		fmt.Fprintf(w, "mkdir -p -- %s\n", shQuote(dir.path))
+	}
+	for i, file := range files {
+		target := shQuote(file.path)
+		switch {
+		case contents[i] == "" && force:
+			fmt.Fprintf(w, ": > %s\n", target)
+		case contents[i] == "":
+			fmt.Fprintf(w, "touch -- %s\n", target)
+		case force:
+			fmt.Fprintf(w, "printf '%%s' %s > %s\n", shQuote(contents[i]), target)
+		default:
+			fmt.Fprintf(w, "[ -e %s ] || printf '%%s' %s > %s\n", target, shQuote(contents[i]), target)
+		}
+	}
+	// permissions last, so a read-only directory can still be filled
+	for _, entry := range append(files, dirs...) {
+		if entry.node.hasPerm {
+			fmt.Fprintf(w, "chmod %04o -- %s\n", entry.node.perm, shQuote(entry.path))
+		}
+	}
+}
+
+// writePowerShell writes the PowerShell version of WriteScript. contents are written
+// with WriteAllText so every PowerShell version produces UTF-8 without a BOM
+func writePowerShell(w io.Writer, dirs, files []scriptEntry, contents []string, force bool) {
+	fmt.Fprintln(w, "# run in the directory the structure should be created in")
+	fmt.Fprintln(w, "$ErrorActionPreference = 'Stop'")
+	for _, dir := range dirs {
+		fmt.Fprintf(w, "New-Item -ItemType Directory -Force -Path %s | Out-Null\n", psQuote(dir.path))
+	}
+	for i, file := range files {
+		target := psQuote(file.path)
+		create := fmt.Sprintf("New-Item -ItemType File -Force -Path %s | Out-Null", target)
+		if contents[i] != "" {
+			create = fmt.Sprintf("[System.IO.File]::WriteAllText((Join-Path $PWD %s), %s)", target, psQuote(contents[i]))
+		}
+		if force {
+			fmt.Fprintln(w, create)
+		} else {
+			fmt.Fprintf(w, "if (-not (Test-Path -LiteralPath %s)) { %s }\n", target, create)
+		}
+	}
+}
+
+// shQuote returns s as a single quoted POSIX shell word
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// psQuote returns s as a single quoted PowerShell string. PowerShell also ends single
+// quoted strings at typographic quotes, so those are doubled like ' is
+func psQuote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\'', '\u2018', '\u2019', '\u201a', '\u201b':
+			sb.WriteRune(r)
+		}
+		sb.WriteRune(r)
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
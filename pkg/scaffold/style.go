@@ -0,0 +1,61 @@
+package scaffold
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// fileStyle is how a kind of file is drawn with PrintOptions.FileColors and Icons
+type fileStyle struct {
+	color string // ANSI escape, empty for the default color
+	icon  string // Nerd Font glyph
+}
+
+// file styles grouped by kind, similar to the default LS_COLORS
+var (
+	styleDefault  = fileStyle{icon: "\uf15b"}
+	styleDir      = fileStyle{color: colorDir, icon: "\uf07b"}
+	styleLink     = fileStyle{color: colorLink, icon: "\uf0c1"}
+	styleGo       = fileStyle{color: "\x1b[36m", icon: "\ue627"}
+	styleSource   = fileStyle{color: "\x1b[32m", icon: "\uf121"}
+	styleScript   = fileStyle{color: "\x1b[1;32m", icon: "\uf489"}
+	styleImage    = fileStyle{color: "\x1b[35m", icon: "\uf1c5"}
+	styleArchive  = fileStyle{color: "\x1b[1;31m", icon: "\uf1c6"}
+	styleDocument = fileStyle{color: "\x1b[33m", icon: "\uf48a"}
+	styleConfig   = fileStyle{color: "\x1b[33m", icon: "\ue615"}
+)
+
+// extensionStyles maps lowercase file extensions to their style
+var extensionStyles = map[string]fileStyle{
+	".go": styleGo,
+
+	".c": styleSource, ".h": styleSource, ".cpp": styleSource, ".rs": styleSource, ".java": styleSource,
+	".py": styleSource, ".js": styleSource, ".ts": styleSource, ".rb": styleSource, ".php": styleSource,
+
+	".sh": styleScript, ".bash": styleScript, ".zsh": styleScript, ".ps1": styleScript,
+
+	".png": styleImage, ".jpg": styleImage, ".jpeg": styleImage, ".gif": styleImage, ".svg": styleImage,
+	".webp": styleImage, ".ico": styleImage, ".bmp": styleImage,
+
+	".zip": styleArchive, ".tar": styleArchive, ".gz": styleArchive, ".tgz": styleArchive, ".bz2": styleArchive,
+	".xz": styleArchive, ".7z": styleArchive, ".rar": styleArchive,
+
+	".md": styleDocument, ".txt": styleDocument, ".pdf": styleDocument, ".rst": styleDocument,
+
+	".json": styleConfig, ".yaml": styleConfig, ".yml": styleConfig, ".toml": styleConfig, ".xml": styleConfig,
+	".ini": styleConfig, ".env": styleConfig, ".mod": styleConfig, ".sum": styleConfig,
+}
+
+// styleOf returns the style a node is drawn with
+func styleOf(node *Node) fileStyle {
+	switch {
+	case node.isDir:
+		return styleDir
+	case node.linkTarget != "":
+		return styleLink
+	}
+	if style, ok := extensionStyles[strings.ToLower(filepath.Ext(node.name))]; ok {
+		return style
+	}
+	return styleDefault
+}
@@ -0,0 +1,55 @@
+package scaffold
+
+import "fmt"
+
+// Problem is something wrong with a parsed structure that Validate found
+type Problem struct {
+	Line    int    // input line of the entry, 0 when unknown
+	Path    string // slash separated path of the entry below the root
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", p.Line, p.Path, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// Validate checks a parsed structure for problems Build would trip over or silently
+// paper over: names that are not a single path element or would escape the output
+// directory, files declared more than once, and names used for a file and a directory
+// in the same place. lines Parse could not read are reported by Parse as a ParseError
+func Validate(root *Node) []Problem {
+	return validateChildren("", root, nil)
+}
+
+func validateChildren(dir string, node *Node, out []Problem) []Problem {
+	seen := make(map[string]*Node)
+	for _, child := range node.children {
+		// not path.Join, it would clean a name like ".." away
+		fullPath := child.name
+		if dir != "" {
+			fullPath = dir + "/" + child.name
+		}
+		if !validName(child.name) {
+			out = append(out, Problem{Line: child.line, Path: fullPath, Message: "name must be a single file or directory name inside the output directory"})
+			continue
+		}
+
+		if first, ok := seen[child.name]; ok {
+			message := "declared more than once"
+			if first.isDir != child.isDir {
+				message = "used for both a file and a directory"
+			}
+			if first.line > 0 {
+				message += fmt.Sprintf(", first on line %d", first.line)
+			}
+			out = append(out, Problem{Line: child.line, Path: fullPath, Message: message})
+		} else {
+			seen[child.name] = child
+		}
+		out = validateChildren(fullPath, child, out)
+	}
+	return out
+}
@@ -0,0 +1,173 @@
+package scaffold
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseYAML reads a YAML structure description. directories are mappings or lists,
+// files are keys with an empty value or plain list items, unless they end in a slash.
+// a key with a string value is a file with that string as its content
+func parseYAML(r io.Reader, opts ParseOptions) (*Node, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return &Node{name: ".", isDir: true}, nil
+		}
+		return nil, fmt.Errorf("error decoding yaml: %w", err)
+	}
+
+	root := &Node{name: ".", isDir: true}
+	if len(doc.Content) == 0 {
+		return root, nil
+	}
+	if err := addYAMLChildren(root, doc.Content[0], opts); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// addYAMLChildren adds the entries described by value to the directory parent
+func addYAMLChildren(parent *Node, value *yaml.Node, opts ParseOptions) error {
+	switch value.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			if err := addYAMLEntry(parent, value.Content[i], value.Content[i+1], opts); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			switch item.Kind {
+			case yaml.ScalarNode:
+				if err := addYAMLEntry(parent, item, nil, opts); err != nil {
+					return err
+				}
+			case yaml.MappingNode:
+				if err := addYAMLChildren(parent, item, opts); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("line %d: unexpected list item in %s", item.Line, parent.name)
+			}
+		}
+	case yaml.ScalarNode:
+		if value.Tag != "!!null" {
+			return fmt.Errorf("line %d: %s is a directory and can not have the value %q", value.Line, parent.name, value.Value)
+		}
+	default:
+		return fmt.Errorf("line %d: unexpected value in %s", value.Line, parent.name)
+	}
+	return nil
+}
+
+// addYAMLEntry adds the entry named by key to parent. value is nil for list items
+func addYAMLEntry(parent *Node, key, value *yaml.Node, opts ParseOptions) error {
+	if key.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: entry names must be plain strings", key.Line)
+	}
+	keyName, err := opts.expand(key.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %v", key.Line, err)
+	}
+	// keys take a trailing (0755) like tree input
+	keyName, perm, hasPerm := splitPermAnnotation(keyName)
+	name := strings.TrimSuffix(keyName, "/")
+	if name == "" {
+		return fmt.Errorf("line %d: empty entry name", key.Line)
+	}
+	if tooDeep(parent.depth+1, opts.MaxNesting) {
+		return fmt.Errorf("line %d: %s is %w, the limit is %d levels", key.Line, name, ErrTooDeep, nestingLimit(opts.MaxNesting))
+	}
+
+	node := &Node{name: name, parent: parent, depth: parent.depth + 1, modTime: yamlModTime(key, value), perm: perm, hasPerm: hasPerm, line: key.Line}
+	isNull := value == nil || (value.Kind == yaml.ScalarNode && value.Tag == "!!null")
+	switch {
+	case isNull:
+		// empty values are files, an empty directory is written as "name/:" or "name: {}"
+		node.isDir = strings.HasSuffix(keyName, "/")
+	case value.Kind == yaml.ScalarNode:
+		node.content = value.Value
+	default:
+		node.isDir = true
+		if err := addYAMLChildren(node, value, opts); err != nil {
+			return err
+		}
+	}
+	parent.children = append(parent.children, node)
+	return nil
+}
+
+// toYAMLNode converts the node into a YAML mapping entry value: a mapping for
+// directories and null for files and empty directories
+func (n *Node) toYAMLNode() *yaml.Node {
+	// empty directories are null too, their key already ends in a slash
+	if !n.isDir || len(n.children) == 0 {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	}
+	out := &yaml.Node{Kind: yaml.MappingNode}
+	for _, child := range n.children {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: child.yamlKey()}
+		if !child.modTime.IsZero() {
+			key.LineComment = mtimeComment + child.modTime.Format(time.RFC3339Nano)
+		}
+		out.Content = append(out.Content, key, child.toYAMLNode())
+	}
+	return out
+}
+
+// yamlKey is the mapping key of a node. directories get a trailing slash so empty
+// directories and extensionless files are read back correctly, and recorded
+// permissions are added like "run.sh (0755)"
+func (n *Node) yamlKey() string {
+	key := n.name
+	if n.isDir {
+		key += "/"
+	}
+	if n.hasPerm {
+		key += fmt.Sprintf(" (%04o)", n.perm)
+	}
+	return key
+}
+
+// mtimeComment starts the comment that holds a modification time in YAML output,
+// e.g. "main.go: # mtime 2024-05-01T10:00:00Z"
+const mtimeComment = "# mtime "
+
+// yamlModTime reads a modification time comment from a mapping key
+func yamlModTime(key, value *yaml.Node) time.Time {
+	for _, comment := range []string{key.LineComment, valueComment(value)} {
+		if stamp, ok := strings.CutPrefix(comment, mtimeComment); ok {
+			if t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(stamp)); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func valueComment(value *yaml.Node) string {
+	if value == nil {
+		return ""
+	}
+	return value.LineComment
+}
+
+// writeYAML writes root as a YAML document with root itself as the only top level key
+func writeYAML(w io.Writer, root *Node) error {
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: root.yamlKey()}
+	if !root.modTime.IsZero() {
+		key.LineComment = mtimeComment + root.modTime.Format(time.RFC3339Nano)
+	}
+	doc := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{key, root.toYAMLNode()}}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("error encoding tree: %w", err)
+	}
+	return enc.Close()
+}